@@ -1,13 +1,21 @@
 package cfg
 
+import "time"
+
 type (
 	// Configuration holds a strongly-typed tree of the configuration
 	Configuration struct {
 		Namespace string
-		Git       GitConfig      `koanf:",squash"`
-		History   HistoryConfig  `koanf:",squash"`
-		Orphan    OrphanConfig   `koanf:",squash"`
-		Resource  ResourceConfig `koanf:",squash"`
+		Git       GitConfig       `koanf:",squash"`
+		GitAuth   GitAuthConfig   `koanf:",squash"`
+		Images    ImagesConfig    `koanf:",squash"`
+		History   HistoryConfig   `koanf:",squash"`
+		Orphan    OrphanConfig    `koanf:",squash"`
+		Retain    RetainConfig    `koanf:",squash"`
+		Resource  ResourceConfig  `koanf:",squash"`
+		Registry  RegistryConfig  `koanf:",squash"`
+		Snapshot  SnapshotConfig  `koanf:",squash"`
+		Discovery DiscoveryConfig `koanf:",squash"`
 		Log       LogConfig
 		Delete    bool
 	}
@@ -17,15 +25,86 @@ type (
 		RepoPath     string `koanf:"repo-path"`
 		Tag          bool   `koanf:"tags"`
 		SortCriteria string `koanf:"sort"`
+		// AllBranches, RemoteBranches and Refs add the history of further refs to the history of HEAD
+		AllBranches    bool     `koanf:"all-branches"`
+		RemoteBranches bool     `koanf:"remote-branches"`
+		Refs           []string `koanf:"refs"`
+		// RepoURL is cloned into memory instead of opening the repository at RepoPath
+		RepoURL    string `koanf:"repo-url"`
+		RepoBranch string `koanf:"repo-branch"`
+		RepoDepth  int    `koanf:"repo-depth"`
+		// TagPattern and StripPrefix map image tags to git commits or tags
+		TagPattern  string `koanf:"tag-pattern"`
+		StripPrefix string `koanf:"strip-prefix"`
+		// Since and Path restrict the commit history to a time window and to a subtree of the repository
+		Since string `koanf:"since"`
+		Path  string `koanf:"path"`
+		// CandidatesFile and Forge replace the repository as the source of the commit hashes or tags
+		CandidatesFile string `koanf:"candidates-file"`
+		Forge          string `koanf:"forge"`
+		ForgeURL       string `koanf:"forge-url"`
+		ForgeProject   string `koanf:"forge-project"`
+		// ProtectReleaseTags keeps the image tags of git tags and of the commits they point to
+		ProtectReleaseTags bool `koanf:"protect-release-tags"`
+	}
+	// GitAuthConfig holds the credentials to clone the repository. It is kept apart from GitConfig so that the
+	// credentials are not logged with the configuration.
+	GitAuthConfig struct {
+		Username       string `koanf:"repo-username"`
+		Token          string `koanf:"repo-token"`
+		TokenFile      string `koanf:"repo-token-file"`
+		SSHKeyFile     string `koanf:"repo-ssh-key"`
+		SSHKeyPassword string `koanf:"repo-ssh-key-password"`
+		KnownHostsFile string `koanf:"repo-known-hosts"`
+	}
+	// ImagesConfig configures which images are cleaned up by the image commands
+	ImagesConfig struct {
+		All                 bool
+		Selector            string
+		ActiveNamespaces    []string `koanf:"active-namespaces"`
+		ActiveAllNamespaces bool     `koanf:"active-all-namespaces"`
+		// PromotionNamespaces are searched for image streams that the images are promoted to
+		PromotionNamespaces []string `koanf:"promotion-namespaces"`
+		// QuotaAware only deletes as many candidates as needed to get below the image stream limits of the namespace
+		QuotaAware    bool `koanf:"quota-aware"`
+		QuotaHeadroom int  `koanf:"quota-headroom"`
+		// ReportStorage resolves the images of the candidates and reports the registry storage freed by deleting them
+		ReportStorage bool `koanf:"report-storage"`
 	}
 	// HistoryConfig configures the history command behaviour
 	HistoryConfig struct {
-		Keep int
+		Keep                int
+		KeepPatchesPerMinor int  `koanf:"keep-patches-per-minor"`
+		KeepMinorsPerMajor  int  `koanf:"keep-minors-per-major"`
+		KeepNewestPerMajor  bool `koanf:"keep-newest-per-major"`
 	}
 	// OrphanConfig configures the orphans command behaviour
 	OrphanConfig struct {
 		OlderThan           string `koanf:"older-than"`
 		OrphanDeletionRegex string `koanf:"deletion-pattern"`
+		BranchPattern       string `koanf:"branch-pattern"`
+	}
+	// RetainConfig configures the retain command behaviour
+	RetainConfig struct {
+		Include      string `koanf:"include"`
+		Exclude      string `koanf:"exclude"`
+		GroupPattern string `koanf:"group-pattern"`
+	}
+	// RegistryConfig configures the access to a Docker Registry HTTP API v2 compatible registry
+	RegistryConfig struct {
+		URL          string        `koanf:"registry-url"`
+		DockerConfig string        `koanf:"docker-config"`
+		Timeout      time.Duration `koanf:"registry-timeout"`
+	}
+	// SnapshotConfig configures sharing the listed cluster resources with later commands
+	SnapshotConfig struct {
+		File   string `koanf:"snapshot-file"`
+		MaxAge string `koanf:"snapshot-max-age"`
+	}
+	// DiscoveryConfig configures the resources that are searched for references and how references are detected
+	DiscoveryConfig struct {
+		ExtraResources []string `koanf:"extra-resources"`
+		ReferenceMode  string   `koanf:"reference-mode"`
 	}
 	// LogConfig configures the log
 	LogConfig struct {
@@ -38,6 +117,7 @@ type (
 		Labels      []string `koanf:"label"`
 		OlderThan   string   `koanf:"older-than"`
 		DeleteAfter string   `koanf:"delete-after"`
+		GroupBy     string   `koanf:"group-by"`
 	}
 )
 
@@ -45,22 +125,78 @@ type (
 func NewDefaultConfig() *Configuration {
 	return &Configuration{
 		Git: GitConfig{
-			CommitLimit:  0,
-			RepoPath:     ".",
-			Tag:          false,
-			SortCriteria: "version",
+			CommitLimit:        0,
+			RepoPath:           ".",
+			Tag:                false,
+			SortCriteria:       "",
+			AllBranches:        false,
+			RemoteBranches:     false,
+			Refs:               []string{},
+			RepoURL:            "",
+			RepoBranch:         "",
+			RepoDepth:          0,
+			TagPattern:         "",
+			StripPrefix:        "",
+			Since:              "",
+			Path:               "",
+			CandidatesFile:     "",
+			Forge:              "",
+			ForgeURL:           "",
+			ForgeProject:       "",
+			ProtectReleaseTags: false,
+		},
+		GitAuth: GitAuthConfig{
+			Username:       "",
+			Token:          "",
+			TokenFile:      "",
+			SSHKeyFile:     "",
+			SSHKeyPassword: "",
+			KnownHostsFile: "",
+		},
+		Images: ImagesConfig{
+			All:                 false,
+			Selector:            "",
+			ActiveNamespaces:    []string{},
+			ActiveAllNamespaces: false,
+			PromotionNamespaces: []string{},
+			QuotaAware:          false,
+			QuotaHeadroom:       1,
+			ReportStorage:       false,
 		},
 		History: HistoryConfig{
-			Keep: 3,
+			Keep:                3,
+			KeepPatchesPerMinor: 0,
+			KeepMinorsPerMajor:  0,
+			KeepNewestPerMajor:  false,
 		},
 		Orphan: OrphanConfig{
 			OlderThan:           "1w",
 			OrphanDeletionRegex: "^[a-z0-9]{40}$",
+			BranchPattern:       "",
+		},
+		Retain: RetainConfig{
+			Include:      "",
+			Exclude:      "",
+			GroupPattern: "",
 		},
 		Resource: ResourceConfig{
 			Labels:      []string{},
 			OlderThan:   "1w",
 			DeleteAfter: "24h",
+			GroupBy:     "",
+		},
+		Registry: RegistryConfig{
+			URL:          "",
+			DockerConfig: "",
+			Timeout:      time.Minute,
+		},
+		Snapshot: SnapshotConfig{
+			File:   "",
+			MaxAge: "10m",
+		},
+		Discovery: DiscoveryConfig{
+			ExtraResources: []string{},
+			ReferenceMode:  "structured",
 		},
 		Delete: false,
 		Log: LogConfig{
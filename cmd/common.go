@@ -1,35 +1,35 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/cleanup"
 	"github.com/appuio/seiso/pkg/git"
 	"github.com/appuio/seiso/pkg/openshift"
+	"github.com/appuio/seiso/pkg/registry"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	gogit "gopkg.in/src-d/go-git.v4"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// DeleteImages deletes a list of image tags
-func DeleteImages(ctx context.Context, imageTags []string, imageName string, namespace string) {
-	for _, inactiveTag := range imageTags {
-		log.Infof("Deleting %s/%s:%s", namespace, imageName, inactiveTag)
-
-		if err := openshift.DeleteImageStreamTag(ctx, namespace, openshift.BuildImageStreamTagName(imageName, inactiveTag)); err != nil {
-			log.WithError(err).Errorf("Failed to delete %s/%s:%s", namespace, imageName, inactiveTag)
-		}
-	}
-}
-
-// PrintImageTags prints the given image tags line by line. In batch mode, only the tag name is printed, otherwise default
-// log with info level
+// PrintImageTags prints the given image tags line by line. In batch mode, only the tag name is printed, or the image
+// stream tag name when several images are cleaned up. Otherwise default log with info level, including the namespace
+// or the registry host of the image.
 func PrintImageTags(imageTags []string, imageName string, namespace string) {
+	if isRegistryMode() {
+		namespace = registry.ParseHost(config.Registry.URL)
+	}
 	if config.Log.Batch {
 		for _, tag := range imageTags {
-			fmt.Println(tag)
+			if isAllImagesMode() {
+				fmt.Println(openshift.BuildImageStreamTagName(imageName, tag))
+			} else {
+				fmt.Println(tag)
+			}
 		}
 	} else {
 		for _, tag := range imageTags {
@@ -48,7 +48,152 @@ func addCommonFlagsForGit(cmd *cobra.Command, defaults *cfg.Configuration) {
 	cmd.PersistentFlags().BoolP("tags", "t", defaults.Git.Tag,
 		"Instead of comparing commit history, it will compare git tags with the existing image tags, removing any image tags that do not match")
 	cmd.PersistentFlags().String("sort", defaults.Git.SortCriteria,
-		fmt.Sprintf("Sort git tags by criteria. Only effective with --tags. Allowed values: [%s, %s]", git.SortOptionVersion, git.SortOptionAlphabetic))
+		fmt.Sprintf("Sort git tags by criteria. Only effective with --tags. Allowed values: [%s, %s, %s, %s]. "+
+			"Defaults to %s, or to the order of the lines with --candidates-file",
+			git.SortOptionVersion, git.SortOptionAlphabetic, git.SortOptionDate, git.SortOptionVersionOrDate, git.DefaultSortOption))
+	cmd.PersistentFlags().Bool("all-branches", defaults.Git.AllBranches,
+		"Compare with the commits of all local branches in addition to HEAD. The commit limit applies per branch.")
+	cmd.PersistentFlags().Bool("remote-branches", defaults.Git.RemoteBranches,
+		"Compare with the commits of all remote-tracking branches in addition to HEAD. The commit limit applies per branch.")
+	cmd.PersistentFlags().StringSlice("refs", defaults.Git.Refs,
+		"Compare with the commits of the refs matching the globs in addition to HEAD, e.g. \"refs/remotes/origin/release/*\". The commit limit applies per ref.")
+	cmd.PersistentFlags().String("tag-pattern", defaults.Git.TagPattern,
+		"Regex extracting the commit hash or git tag from image tags, using the group named \"sha\" (or \"version\" with --tags) or the first group, "+
+			"e.g. \"^(?P<version>.+)-(?P<sha>[0-9a-f]{7})$\". Image tags not matching the regex are ignored.")
+	cmd.PersistentFlags().String("strip-prefix", defaults.Git.StripPrefix,
+		"Prefix removed from git tags and image tags before comparing them, e.g. \"v\" to match git tag \"v1.4.2\" with image tag \"1.4.2\"")
+	cmd.PersistentFlags().String("since", defaults.Git.Since,
+		"Only compare with commits committed within this duration, e.g. \"30d\" or \"1y6mo\". Not effective with --tags.")
+	cmd.PersistentFlags().String("path", defaults.Git.Path,
+		"Only compare with commits changing this file or directory of the repository, e.g. \"services/api\" in a monorepo. Not effective with --tags.")
+	cmd.PersistentFlags().String("repo-url", defaults.Git.RepoURL,
+		"URL of a Git repository to clone into memory instead of using --repo-path, e.g. \"https://git.example.com/team/app.git\"")
+	cmd.PersistentFlags().String("repo-branch", defaults.Git.RepoBranch,
+		"Only clone this branch of --repo-url, or read the commits of this branch of --forge-project. Defaults to all branches (the default branch for --forge).")
+	cmd.PersistentFlags().Int("repo-depth", defaults.Git.RepoDepth,
+		"Only clone the last <n> commits of --repo-url. Use 0 (zero) for the full history.")
+	cmd.PersistentFlags().Bool("protect-release-tags", defaults.Git.ProtectReleaseTags,
+		"Never delete the image tags of the commits that git tags point to, or named like git tags, even outside of --commit-limit. Not effective with --tags.")
+	cmd.PersistentFlags().String("candidates-file", defaults.Git.CandidatesFile,
+		"File with the commit hashes (or git tags with --tags) to compare, one per line and newest first, instead of a git repository. Use \"-\" for stdin.")
+	cmd.PersistentFlags().String("forge", defaults.Git.Forge,
+		fmt.Sprintf("Read the commits (or tags with --tags) of --forge-project from the REST API of the forge instead of a git repository. Allowed values: [%s, %s]",
+			git.ForgeGitHub, git.ForgeGitLab))
+	cmd.PersistentFlags().String("forge-url", defaults.Git.ForgeURL,
+		"URL of the forge API, e.g. \"https://gitlab.example.com\". Defaults to GitHub.com or GitLab.com.")
+	cmd.PersistentFlags().String("forge-project", defaults.Git.ForgeProject,
+		"Project of --forge, e.g. \"team/app\"")
+	cmd.PersistentFlags().String("repo-username", defaults.GitAuth.Username,
+		"Username for the token of an HTTPS --repo-url. The token is read from --repo-token-file or $SEISO_REPO_TOKEN.")
+	cmd.PersistentFlags().String("repo-token-file", defaults.GitAuth.TokenFile,
+		"File containing the access token for an HTTPS --repo-url or for --forge, e.g. a mounted Secret")
+	cmd.PersistentFlags().String("repo-ssh-key", defaults.GitAuth.SSHKeyFile,
+		"File containing the private SSH key for an SSH --repo-url, e.g. a mounted Secret. The passphrase is read from $SEISO_REPO_SSH_KEY_PASSWORD.")
+	cmd.PersistentFlags().String("repo-known-hosts", defaults.GitAuth.KnownHostsFile,
+		"known_hosts file to verify the host key of an SSH --repo-url with --repo-ssh-key, e.g. a mounted ConfigMap. Defaults to ~/.ssh/known_hosts or $SSH_KNOWN_HOSTS, which usually do not exist in a container.")
+}
+
+// validateGitArguments validates the common git flags
+func validateGitArguments() error {
+	if config.Git.Tag && config.Git.SortCriteria != "" && !git.IsValidSortValue(config.Git.SortCriteria) {
+		return fmt.Errorf("invalid sort flag provided: %v", config.Git.SortCriteria)
+	}
+	if config.Git.RepoDepth < 0 {
+		return fmt.Errorf("repo-depth flag must not be negative: %d", config.Git.RepoDepth)
+	}
+	filter, err := git.NewCommitFilter(&config.Git)
+	if err != nil {
+		return err
+	}
+	if err := validateCandidateSource(config.Git, filter); err != nil {
+		return err
+	}
+	if _, err := newTagMapping(config.Git); err != nil {
+		return fmt.Errorf("could not parse tag-pattern flag: %w", err)
+	}
+	return nil
+}
+
+// validateCandidateSource validates that at most one source replaces the git repository and that it supports the
+// given flags
+func validateCandidateSource(c cfg.GitConfig, filter git.CommitFilter) error {
+	sources := 0
+	for _, source := range []string{c.RepoURL, c.CandidatesFile, c.Forge} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("only one of --repo-url, --candidates-file and --forge can be given")
+	}
+	if c.Forge != "" {
+		if !git.IsValidForge(c.Forge) {
+			return fmt.Errorf("invalid forge flag provided: %v", c.Forge)
+		}
+		if c.ForgeProject == "" {
+			return fmt.Errorf("--forge requires --forge-project")
+		}
+	}
+	if !git.HasRepository(&c) && len(git.RefPatterns(&c)) > 0 {
+		return fmt.Errorf("--all-branches, --remote-branches and --refs require a git repository")
+	}
+	if c.CandidatesFile != "" && filter.IsEnabled() {
+		return fmt.Errorf("--since and --path are not supported with --candidates-file")
+	}
+	if c.ProtectReleaseTags && c.CandidatesFile != "" {
+		return fmt.Errorf("--protect-release-tags is not supported with --candidates-file")
+	}
+	return nil
+}
+
+// openRepository opens the git repository once per command, so that a repository given by --repo-url is cloned only
+// once. It returns nil if the candidates are read from a file or a forge.
+func openRepository(c *cfg.GitConfig, auth *cfg.GitAuthConfig) (*gogit.Repository, error) {
+	if !git.HasRepository(c) {
+		return nil, nil
+	}
+	return git.OpenRepository(c, auth)
+}
+
+// getReleases returns the commits of the git tags by tag name, if the image tags of releases are protected
+func getReleases(c *cfg.GitConfig, auth *cfg.GitAuthConfig, repository *gogit.Repository) (map[string]string, error) {
+	if !c.ProtectReleaseTags || c.Tag {
+		return nil, nil
+	}
+	releases, err := git.GetTaggedCommits(c, auth, repository)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving release tags failed: %w", err)
+	}
+	log.WithField("releases", releases).Debug("Found release tags")
+	return releases, nil
+}
+
+// newMatchOption returns how image tags are compared with the git candidates
+func newMatchOption(c cfg.GitConfig) cleanup.MatchOption {
+	if c.Tag {
+		return cleanup.MatchOptionExact
+	}
+	return cleanup.MatchOptionPrefix
+}
+
+// newTagMapping returns the mapping of image tags to the git commits or git tags
+func newTagMapping(c cfg.GitConfig) (cleanup.TagMapping, error) {
+	mapping := cleanup.TagMapping{
+		Group:       cleanup.TagMappingGroupSha,
+		StripPrefix: c.StripPrefix,
+	}
+	if c.Tag {
+		mapping.Group = cleanup.TagMappingGroupVersion
+	}
+	if c.TagPattern == "" {
+		return mapping, nil
+	}
+	pattern, err := regexp.Compile(c.TagPattern)
+	if err != nil {
+		return mapping, err
+	}
+	mapping.Pattern = pattern
+	return mapping, nil
 }
 
 // toListOptions converts "key=value"-labels to Kubernetes LabelSelector
@@ -8,6 +8,7 @@ import (
 	"github.com/appuio/seiso/cfg"
 	"github.com/appuio/seiso/pkg/configmap"
 	"github.com/appuio/seiso/pkg/kubernetes"
+	"github.com/appuio/seiso/pkg/util"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -40,6 +41,8 @@ func init() {
 		"Keep most current <k> ConfigMaps; does not include currently used ConfigMaps (if detected)")
 	configMapCmd.PersistentFlags().String("older-than", defaults.Resource.OlderThan,
 		"Delete ConfigMaps that are older than the duration, e.g. [1y2mo3w4d5h6m7s]")
+	configMapCmd.PersistentFlags().String("group-by", defaults.Resource.GroupBy,
+		"Apply --keep and --older-than per group of ConfigMaps: \"kustomize\" (name without hash suffix) or \"label:<key>\"")
 }
 
 func validateConfigMapCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
@@ -55,6 +58,9 @@ func validateConfigMapCommandInput(cmd *cobra.Command, args []string) (returnErr
 	if _, err := parseCutOffDateTime(config.Resource.OlderThan); err != nil {
 		return fmt.Errorf("could not parse older-than flag: %w", err)
 	}
+	if err := util.ValidateGroupBy(config.Resource.GroupBy); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -64,13 +70,22 @@ func executeConfigMapCleanupCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("cannot initiate kubernetes client: %w", err)
 	}
 
+	helper, err := newKubernetesHelper()
+	if err != nil {
+		return err
+	}
+
 	ctx := context.Background()
 	c := config.Resource
 	namespace := config.Namespace
 	service := configmap.NewConfigMapsService(
 		coreClient.ConfigMaps(namespace),
-		kubernetes.New(),
-		configmap.ServiceConfiguration{Batch: config.Log.Batch})
+		helper,
+		configmap.ServiceConfiguration{
+			Batch:         config.Log.Batch,
+			Resources:     extraResources,
+			ReferenceMode: config.Discovery.ReferenceMode,
+		})
 
 	log.WithField("namespace", namespace).Debug("Getting ConfigMaps")
 	foundConfigMaps, err := service.List(ctx, toListOptions(c.Labels))
@@ -84,8 +99,7 @@ func executeConfigMapCleanupCommand(cmd *cobra.Command, args []string) error {
 	}
 
 	cutOffDateTime, _ := parseCutOffDateTime(c.OlderThan)
-	filteredConfigMaps := service.FilterByTime(unusedConfigMaps, cutOffDateTime)
-	filteredConfigMaps = service.FilterByMaxCount(filteredConfigMaps, config.History.Keep)
+	filteredConfigMaps := service.FilterPerGroup(unusedConfigMaps, c.GroupBy, cutOffDateTime, config.History.Keep)
 
 	if config.Delete {
 		err := service.Delete(ctx, filteredConfigMaps)
@@ -97,6 +111,7 @@ func executeConfigMapCleanupCommand(cmd *cobra.Command, args []string) error {
 			"namespace":  namespace,
 			"keep":       config.History.Keep,
 			"older_than": c.OlderThan,
+			"group_by":   c.GroupBy,
 		}).Info("Showing results")
 		service.Print(filteredConfigMaps)
 	}
@@ -7,14 +7,13 @@ import (
 	"github.com/appuio/seiso/cfg"
 	"github.com/appuio/seiso/pkg/cleanup"
 	"github.com/appuio/seiso/pkg/git"
-	"github.com/appuio/seiso/pkg/openshift"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var (
 	historyCmd = &cobra.Command{
-		Use:          "history [NAMESPACE/IMAGE]",
+		Use:          "history [NAMESPACE/IMAGE | --all]",
 		Aliases:      []string{"hist"},
 		Short:        "Clean up excessive image tags",
 		Long:         `Clean up excessive image tags matching the commit hashes (prefix) of the git repository`,
@@ -30,77 +29,116 @@ func init() {
 	defaults := cfg.NewDefaultConfig()
 
 	addCommonFlagsForGit(historyCmd, defaults)
+	addCommonFlagsForRegistry(historyCmd, defaults)
+	addCommonFlagsForImageSelection(historyCmd, defaults)
+	addCommonFlagsForActiveImages(historyCmd, defaults)
+	addCommonFlagsForQuota(historyCmd, defaults)
+	addCommonFlagsForStorageReport(historyCmd, defaults)
 	historyCmd.PersistentFlags().IntP("keep", "k", defaults.History.Keep,
 		"Keep most current <k> images. Does not include currently used image tags (if detected).")
-
+	historyCmd.PersistentFlags().Int("keep-patches-per-minor", defaults.History.KeepPatchesPerMinor,
+		"Additionally keep the newest <n> versions of each minor version, e.g. 1.2.x. Only effective with --tags.")
+	historyCmd.PersistentFlags().Int("keep-minors-per-major", defaults.History.KeepMinorsPerMajor,
+		"Additionally keep the newest <n> minor versions of each major version, e.g. 1.x, with their newest patch versions. Only effective with --tags.")
+	historyCmd.PersistentFlags().Bool("keep-newest-per-major", defaults.History.KeepNewestPerMajor,
+		"Never delete the newest version of each major version. Only effective with --tags.")
 }
 
 func validateHistoryCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
 	defer showUsageOnError(cmd, returnErr)
-	if len(args) == 0 {
-		return missingImageNameError(config.Namespace)
+	if err := validateImageArguments(args); err != nil {
+		return err
 	}
-	namespace, image, err := splitNamespaceAndImagestream(args[0])
-	if err != nil {
-		return fmt.Errorf("could not parse image name: %w", err)
+	if err := validateGitArguments(); err != nil {
+		return err
+	}
+	if err := validateQuotaArguments(); err != nil {
+		return err
+	}
+	if err := validateStorageReportArguments(); err != nil {
+		return err
 	}
-	if config.Git.Tag && !git.IsValidSortValue(config.Git.SortCriteria) {
-		return fmt.Errorf("invalid sort flag provided: %v", config.Git.SortCriteria)
+	retention := newVersionRetention(config.History)
+	if retention.PatchesPerMinor < 0 || retention.MinorsPerMajor < 0 {
+		return fmt.Errorf("version retention flags must not be negative")
+	}
+	if retention.IsEnabled() && !config.Git.Tag {
+		return fmt.Errorf("version retention flags require --tags")
 	}
-	log.WithFields(log.Fields{
-		"namespace": namespace,
-		"image":     image,
-	}).Debug("Using image config")
-	config.Namespace = namespace
 	return nil
 }
 
+// newVersionRetention returns the rules to keep image tags by their semantic version
+func newVersionRetention(c cfg.HistoryConfig) git.VersionRetention {
+	return git.VersionRetention{
+		PatchesPerMinor: c.KeepPatchesPerMinor,
+		MinorsPerMajor:  c.KeepMinorsPerMajor,
+		NewestPerMajor:  c.KeepNewestPerMajor,
+	}
+}
+
 // ExecuteHistoryCleanupCommand executes the history cleanup command
 func ExecuteHistoryCleanupCommand(cmd *cobra.Command, args []string) error {
 	c := config.History
 	ctx := context.Background()
-	namespace, imageName, _ := splitNamespaceAndImagestream(args[0])
+	namespace := config.Namespace
 
-	imageStreamObjectTags, err := openshift.GetImageStreamTags(ctx, namespace, imageName)
+	repository, err := newImageRepository(ctx, namespace)
 	if err != nil {
-		return fmt.Errorf("could not retrieve image stream '%s/%s': %w", namespace, imageName, err)
+		return err
 	}
-
-	var imageStreamTags []string
-	for _, imageTag := range imageStreamObjectTags {
-		imageStreamTags = append(imageStreamTags, imageTag.Tag)
+	imageNames, err := resolveImages(ctx, repository, args)
+	if err != nil {
+		return err
 	}
 
-	matchOption := cleanup.MatchOptionPrefix
-	if config.Git.Tag {
-		matchOption = cleanup.MatchOptionExact
-	}
+	tagMapping, _ := newTagMapping(config.Git)
 
-	gitCandidates, err := git.GetGitCandidateList(&config.Git)
+	gitRepository, err := openRepository(&config.Git, &config.GitAuth)
 	if err != nil {
 		return err
 	}
-	var matchingTags = cleanup.GetMatchingTags(&gitCandidates, &imageStreamTags, matchOption)
-
-	activeImageStreamTags, err := openshift.GetActiveImageStreamTags(ctx, namespace, imageName, matchingTags)
+	gitCandidates, err := git.GetGitCandidateList(&config.Git, &config.GitAuth, gitRepository)
 	if err != nil {
-		return fmt.Errorf("could not retrieve active image stream tags for '%s/%s': %w", namespace, imageName, err)
+		return err
 	}
-
-	inactiveTags := cleanup.GetInactiveImageTags(&activeImageStreamTags, &matchingTags)
-	inactiveTags = cleanup.LimitTags(&inactiveTags, c.Keep)
-	if len(inactiveTags) == 0 {
-		log.WithFields(log.Fields{
-			"\n - namespace": namespace,
-			"\n - 📺 image":   imageName,
-		}).Info("No inactive image stream tags found")
-		return nil
+	releases, err := getReleases(&config.Git, &config.GitAuth, gitRepository)
+	if err != nil {
+		return err
 	}
-	if config.Delete {
-		DeleteImages(ctx, inactiveTags, imageName, namespace)
-	} else {
+	quota, err := newQuota(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	options := cleanup.HistoryOptions{
+		MatchOption:      newMatchOption(config.Git),
+		TagMapping:       tagMapping,
+		Keep:             c.Keep,
+		VersionRetention: newVersionRetention(c),
+		Releases:         releases,
+		Quota:            quota,
+	}
+
+	blobIndex := newBlobIndex(ctx, repository)
+
+	return cleanupImages(imageNames, func(imageName string) (int, error) {
+		inactiveTags, err := cleanup.GetHistoryCandidates(ctx, repository, imageName, gitCandidates, options)
+		if err != nil {
+			return 0, fmt.Errorf("could not determine history candidates for '%s/%s': %w", namespace, imageName, err)
+		}
+		if len(inactiveTags) == 0 {
+			log.WithFields(log.Fields{
+				"\n - namespace": namespace,
+				"\n - 📺 image":   imageName,
+			}).Info("No inactive image stream tags found")
+			return 0, nil
+		}
+		reportStorage(ctx, blobIndex, imageName, inactiveTags)
+		if config.Delete {
+			return len(inactiveTags), repository.DeleteTags(ctx, imageName, inactiveTags)
+		}
 		log.Infof("Showing results for --commit-limit=%d and --keep=%d", config.Git.CommitLimit, c.Keep)
 		PrintImageTags(inactiveTags, imageName, namespace)
-	}
-	return nil
+		return len(inactiveTags), nil
+	})
 }
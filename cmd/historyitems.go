@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/cleanup"
+	"github.com/appuio/seiso/pkg/openshift"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	historyItemsCommandLongDescription = `Clean up the history of image stream tags. A tag that is pushed repeatedly, e.g. "latest",
+keeps every previous image in its history. The newest items of each tag are kept, as well as the items whose image is
+referenced by digest or running in the cluster. The tags themselves are never deleted.`
+)
+
+var (
+	// historyItemsCmd represents a cobra command to remove old items from the history of the image stream tags
+	historyItemsCmd = &cobra.Command{
+		Use:          "history-items [NAMESPACE/IMAGE | --all]",
+		Short:        "Clean up old history items of image stream tags",
+		Long:         historyItemsCommandLongDescription,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		PreRunE:      validateHistoryItemsCommandInput,
+		RunE:         ExecuteHistoryItemsCleanupCommand,
+	}
+)
+
+func init() {
+	imagesCmd.AddCommand(historyItemsCmd)
+	defaults := cfg.NewDefaultConfig()
+
+	addCommonFlagsForImageSelection(historyItemsCmd, defaults)
+	addCommonFlagsForActiveImages(historyItemsCmd, defaults)
+	historyItemsCmd.PersistentFlags().BoolP("delete", "d", defaults.Delete, "Effectively delete history items found")
+	historyItemsCmd.PersistentFlags().IntP("keep", "k", defaults.History.Keep,
+		"Keep the newest <k> items of each tag, including the current image of the tag. Does not include images in use.")
+}
+
+func validateHistoryItemsCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
+	defer showUsageOnError(cmd, returnErr)
+	if isRegistryMode() {
+		return errors.New("history-items is only supported for image streams, not with --registry-url")
+	}
+	if err := validateImageArguments(args); err != nil {
+		return err
+	}
+	if config.History.Keep < 1 {
+		return fmt.Errorf("keep flag must be at least 1 to keep the current image of the tags: %d", config.History.Keep)
+	}
+	return nil
+}
+
+// ExecuteHistoryItemsCleanupCommand executes the history items cleanup command
+func ExecuteHistoryItemsCleanupCommand(_ *cobra.Command, args []string) error {
+	ctx := context.Background()
+	namespace := config.Namespace
+
+	repository, err := newImageRepository(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	itemRepository, ok := repository.(cleanup.HistoryItemRepository)
+	if !ok {
+		return errors.New("the image repository does not support history items")
+	}
+	imageNames, err := resolveImages(ctx, repository, args)
+	if err != nil {
+		return err
+	}
+	options := cleanup.HistoryItemOptions{Keep: config.History.Keep}
+
+	return cleanupImages(imageNames, func(imageName string) (int, error) {
+		candidates, err := cleanup.GetHistoryItemCandidates(ctx, itemRepository, imageName, options)
+		if err != nil {
+			return 0, fmt.Errorf("could not determine history item candidates for '%s/%s': %w", namespace, imageName, err)
+		}
+		count := 0
+		for _, digests := range candidates {
+			count += len(digests)
+		}
+		if count == 0 {
+			log.WithFields(log.Fields{
+				"\n - namespace": namespace,
+				"\n - 📺 image":   imageName,
+			}).Info("No history items to clean up found")
+			return 0, nil
+		}
+		if config.Delete {
+			return count, itemRepository.DeleteHistoryItems(ctx, imageName, candidates)
+		}
+		log.Infof("Showing results for --keep=%d", config.History.Keep)
+		printHistoryItems(candidates, imageName, namespace)
+		return count, nil
+	})
+}
+
+// printHistoryItems prints the history items as tag and digest, sorted by tag. In batch mode, only the tag and digest
+// are printed, or with the image name when several images are cleaned up.
+func printHistoryItems(candidates map[string][]string, imageName, namespace string) {
+	tags := make([]string, 0, len(candidates))
+	for tag := range candidates {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		for _, digest := range candidates[tag] {
+			switch {
+			case config.Log.Batch && isAllImagesMode():
+				fmt.Printf("%s@%s\n", openshift.BuildImageStreamTagName(imageName, tag), digest)
+			case config.Log.Batch:
+				fmt.Printf("%s@%s\n", tag, digest)
+			default:
+				log.Infof("Found history item candidate: %s/%s:%s@%s", namespace, imageName, tag, digest)
+			}
+		}
+	}
+}
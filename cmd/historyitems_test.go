@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateHistoryItemsCommandInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  cfg.Configuration
+		wantErr bool
+	}{
+		{
+			name:   "ShouldAcceptKeep",
+			args:   []string{"namespace/image"},
+			config: cfg.Configuration{History: cfg.HistoryConfig{Keep: 1}},
+		},
+		{
+			name:    "ShouldThrowError_IfKeepIsZero",
+			args:    []string{"namespace/image"},
+			config:  cfg.Configuration{History: cfg.HistoryConfig{Keep: 0}},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfRegistryMode",
+			args: []string{"namespace/image"},
+			config: cfg.Configuration{
+				History:  cfg.HistoryConfig{Keep: 3},
+				Registry: cfg.RegistryConfig{URL: "registry.example.com"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config = &tt.config
+			err := validateHistoryItemsCommandInput(&cobra.Command{}, tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
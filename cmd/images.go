@@ -1,9 +1,19 @@
 package cmd
 
 import (
+	"context"
 	"errors"
-	"github.com/spf13/cobra"
+	"fmt"
 	"strings"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/cleanup"
+	"github.com/appuio/seiso/pkg/kubernetes"
+	"github.com/appuio/seiso/pkg/openshift"
+	"github.com/appuio/seiso/pkg/registry"
+	"github.com/appuio/seiso/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 )
 
 // imagesCmd represents the images command
@@ -39,3 +49,243 @@ func splitNamespaceAndImagestream(repo string) (namespace string, image string,
 	}
 	return namespace, image, nil
 }
+
+// newImageRepository creates the repository providing the image tags, either the registry given with --registry-url or
+// the OpenShift ImageStreams of the namespace
+func newImageRepository(ctx context.Context, namespace string) (cleanup.ImageRepository, error) {
+	helper, err := newKubernetesHelper()
+	if err != nil {
+		return nil, err
+	}
+	activeOptions, err := newActiveImageOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if isRegistryMode() {
+		if len(activeOptions.PromotionNamespaces) > 0 {
+			return nil, errors.New("--promotion-namespaces is not supported with --registry-url")
+		}
+		client, err := newRegistryClient()
+		if err != nil {
+			return nil, fmt.Errorf("cannot initiate registry client: %w", err)
+		}
+		return registry.NewRepository(client, helper, namespace, activeOptions), nil
+	}
+	imageClient, err := openshift.NewImageV1Client()
+	if err != nil {
+		return nil, fmt.Errorf("cannot initiate image client: %w", err)
+	}
+	return openshift.NewImageStreamRepository(imageClient, helper, namespace, activeOptions), nil
+}
+
+// newActiveImageOptions determines the additional namespaces that are searched for active images
+func newActiveImageOptions(ctx context.Context) (openshift.ActiveImageOptions, error) {
+	options := openshift.ActiveImageOptions{
+		Namespaces:          config.Images.ActiveNamespaces,
+		PromotionNamespaces: config.Images.PromotionNamespaces,
+		ReferenceMode:       config.Discovery.ReferenceMode,
+		Resources:           extraResources,
+	}
+	if !config.Images.ActiveAllNamespaces {
+		return options, nil
+	}
+	dynamicClient, err := kubernetes.NewDynamicClient()
+	if err != nil {
+		return options, fmt.Errorf("cannot initiate kubernetes dynamic client: %w", err)
+	}
+	namespaces, err := openshift.ListNamespaces(ctx, dynamicClient)
+	if err != nil {
+		return options, fmt.Errorf("could not list namespaces to search for active images: %w", err)
+	}
+	log.WithField("namespaces", namespaces).Debug("Searching all namespaces for active images")
+	options.Namespaces = namespaces
+	return options, nil
+}
+
+// addCommonFlagsForActiveImages sets up the flags to search other namespaces for active images
+func addCommonFlagsForActiveImages(cmd *cobra.Command, defaults *cfg.Configuration) {
+	cmd.PersistentFlags().StringSlice("active-namespaces", defaults.Images.ActiveNamespaces,
+		"Additional namespaces to search for resources using an image tag, e.g. when other namespaces pull images from this namespace")
+	cmd.PersistentFlags().Bool("active-all-namespaces", defaults.Images.ActiveAllNamespaces,
+		"Search all namespaces (or OpenShift projects) you can list for resources using an image tag")
+	cmd.PersistentFlags().StringSlice("promotion-namespaces", defaults.Images.PromotionNamespaces,
+		"Namespaces whose image streams the images are promoted to. Tags referenced by the \"from\" of a spec tag, or whose image is in the history of another image stream, are kept.")
+}
+
+// addCommonFlagsForQuota sets up the flags to clean up only as much as needed to get below the image stream limits
+func addCommonFlagsForQuota(cmd *cobra.Command, defaults *cfg.Configuration) {
+	cmd.PersistentFlags().Bool("quota-aware", defaults.Images.QuotaAware,
+		"Only delete as many of the oldest candidates as needed to get below the limits of \"openshift.io/image-tags\" and \"openshift.io/images\" "+
+			"in the LimitRanges and ResourceQuotas of the namespace")
+	cmd.PersistentFlags().Int("quota-headroom", defaults.Images.QuotaHeadroom,
+		"Amount of tags and images that should be available below the limits after the cleanup. Only effective with --quota-aware.")
+}
+
+// validateQuotaArguments validates the quota flags
+func validateQuotaArguments() error {
+	if !config.Images.QuotaAware {
+		return nil
+	}
+	if isRegistryMode() {
+		return errors.New("--quota-aware is not supported with --registry-url")
+	}
+	if config.Images.QuotaHeadroom < 0 {
+		return fmt.Errorf("quota-headroom flag must not be negative: %d", config.Images.QuotaHeadroom)
+	}
+	return nil
+}
+
+// newQuota reads the image stream limits of the namespace if the cleanup is quota aware, or returns nil otherwise
+func newQuota(ctx context.Context, namespace string) (*cleanup.Quota, error) {
+	if !config.Images.QuotaAware {
+		return nil, nil
+	}
+	coreClient, err := kubernetes.NewCoreV1Client()
+	if err != nil {
+		return nil, fmt.Errorf("cannot initiate kubernetes client: %w", err)
+	}
+	limits, err := openshift.GetImageStreamLimits(ctx, coreClient, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("could not read image stream limits of namespace '%s': %w", namespace, err)
+	}
+	if !limits.IsLimited() {
+		log.WithField("namespace", namespace).Info("No image stream limits found, nothing needs to be deleted")
+	}
+	return &cleanup.Quota{ImageStreamLimits: limits, Headroom: config.Images.QuotaHeadroom, DryRun: !config.Delete}, nil
+}
+
+// addCommonFlagsForStorageReport sets up the flag to report the storage that is reclaimable by deleting the candidates
+func addCommonFlagsForStorageReport(cmd *cobra.Command, defaults *cfg.Configuration) {
+	cmd.PersistentFlags().Bool("report-storage", defaults.Images.ReportStorage,
+		"Resolve the images of the candidates and report the reclaimable registry storage per tag and in total. "+
+			"Layers shared with kept tags of the image stream are not counted.")
+}
+
+// validateStorageReportArguments validates the storage report flag
+func validateStorageReportArguments() error {
+	if config.Images.ReportStorage && isRegistryMode() {
+		return errors.New("--report-storage is not supported with --registry-url")
+	}
+	return nil
+}
+
+// newBlobIndex resolves the blobs of all images in the namespace once per run, if the storage report is enabled. Since
+// the report is informative, failures are logged and disable the report, but do not stop the cleanup.
+func newBlobIndex(ctx context.Context, repository cleanup.ImageRepository) *cleanup.BlobIndex {
+	if !config.Images.ReportStorage {
+		return nil
+	}
+	index, err := cleanup.NewBlobIndex(ctx, repository)
+	if err != nil {
+		log.WithError(err).Warnf("Could not resolve the images of %s, not reporting reclaimable storage", config.Namespace)
+		return nil
+	}
+	return index
+}
+
+// reportStorage logs the storage that is reclaimable by deleting the candidates of the image, if the blob index was
+// built. Since the report is informative, failures are logged but do not stop the cleanup.
+func reportStorage(ctx context.Context, index *cleanup.BlobIndex, imageName string, candidates []string) {
+	if index == nil {
+		return
+	}
+	report, err := cleanup.GetReclaimableStorage(ctx, index, imageName, candidates)
+	if err != nil {
+		log.WithError(err).Warnf("Could not determine reclaimable storage of %s/%s", config.Namespace, imageName)
+		return
+	}
+	for _, tag := range candidates {
+		log.WithField("bytes", report.Tags[tag]).Infof("Reclaimable storage of %s/%s:%s: %s",
+			config.Namespace, imageName, tag, util.FormatBytes(report.Tags[tag]))
+	}
+	log.WithField("bytes", report.Total).Infof("Reclaimable storage of %d tags of %s/%s: %s",
+		len(candidates), config.Namespace, imageName, util.FormatBytes(report.Total))
+}
+
+// addCommonFlagsForImageSelection sets up the flags to clean up several images of a namespace in one run
+func addCommonFlagsForImageSelection(cmd *cobra.Command, defaults *cfg.Configuration) {
+	cmd.PersistentFlags().BoolP("all", "a", defaults.Images.All,
+		"Clean up all image streams in the namespace instead of a single image. The git history is only read once.")
+	cmd.PersistentFlags().String("selector", defaults.Images.Selector,
+		"Clean up all image streams in the namespace matching the label selector, e.g. \"app=example\". Implies --all.")
+}
+
+// isAllImagesMode returns true if several images are cleaned up in one run
+func isAllImagesMode() bool {
+	return config.Images.All || config.Images.Selector != ""
+}
+
+// validateImageArguments validates the image argument or the image selection flags and sets the namespace
+func validateImageArguments(args []string) error {
+	if isAllImagesMode() {
+		if len(args) > 0 {
+			return fmt.Errorf("image argument %q is not allowed with --all or --selector, use --namespace instead", args[0])
+		}
+		if isRegistryMode() {
+			return errors.New("--all and --selector are not supported with --registry-url")
+		}
+		return nil
+	}
+	if len(args) == 0 {
+		return missingImageNameError(config.Namespace)
+	}
+	namespace, image, err := parseImageArgument(args[0])
+	if err != nil {
+		return fmt.Errorf("could not parse image name: %w", err)
+	}
+	log.WithFields(log.Fields{
+		"namespace": namespace,
+		"image":     image,
+	}).Debug("Using image config")
+	config.Namespace = namespace
+	return nil
+}
+
+// resolveImages returns the names of the images to clean up, either from the image argument or by listing the images
+// of the repository
+func resolveImages(ctx context.Context, repository cleanup.ImageRepository, args []string) ([]string, error) {
+	if !isAllImagesMode() {
+		_, imageName, err := parseImageArgument(args[0])
+		return []string{imageName}, err
+	}
+	lister, ok := repository.(cleanup.ImageLister)
+	if !ok {
+		return nil, errors.New("the image repository does not support listing images")
+	}
+	imageNames, err := lister.ListImages(ctx, config.Images.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("could not list images in '%s': %w", config.Namespace, err)
+	}
+	log.WithFields(log.Fields{
+		"namespace": config.Namespace,
+		"selector":  config.Images.Selector,
+		"images":    imageNames,
+	}).Debug("Found images")
+	return imageNames, nil
+}
+
+// cleanupImages runs the cleanup function for each image and reports the amount of candidates per image. A failure
+// of a single image does not stop the cleanup of the other images, but is returned as error at the end.
+func cleanupImages(imageNames []string, cleanupImage func(imageName string) (int, error)) error {
+	if len(imageNames) == 1 && !isAllImagesMode() {
+		_, err := cleanupImage(imageNames[0])
+		return err
+	}
+	results := log.Fields{}
+	failed := 0
+	for _, imageName := range imageNames {
+		count, err := cleanupImage(imageName)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to clean up image %s/%s", config.Namespace, imageName)
+			results[imageName] = "failed"
+			failed++
+			continue
+		}
+		results[imageName] = count
+	}
+	log.WithFields(results).Infof("Processed %d images in namespace %s (tag candidates per image)", len(imageNames), config.Namespace)
+	if failed > 0 {
+		return fmt.Errorf("failed to clean up %d of %d images", failed, len(imageNames))
+	}
+	return nil
+}
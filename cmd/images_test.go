@@ -3,6 +3,7 @@ package cmd
 import (
 	"testing"
 
+	"github.com/appuio/seiso/cfg"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -75,3 +76,53 @@ func Test_SplitNamespaceAndImagestream(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateImageArguments(t *testing.T) {
+	tests := map[string]struct {
+		args              []string
+		config            cfg.Configuration
+		expectedNamespace string
+		wantErr           bool
+	}{
+		"ShouldSetNamespace_FromImageArgument": {
+			args:              []string{"namespace/image"},
+			expectedNamespace: "namespace",
+		},
+		"ShouldThrowError_IfImageArgumentIsMissing": {
+			wantErr: true,
+		},
+		"ShouldAllowMissingImageArgument_IfAll": {
+			config:            cfg.Configuration{Images: cfg.ImagesConfig{All: true}},
+			expectedNamespace: "currently-active-ns",
+		},
+		"ShouldAllowMissingImageArgument_IfSelector": {
+			config:            cfg.Configuration{Images: cfg.ImagesConfig{Selector: "app=example"}},
+			expectedNamespace: "currently-active-ns",
+		},
+		"ShouldThrowError_IfImageArgumentAndAll": {
+			args:    []string{"namespace/image"},
+			config:  cfg.Configuration{Images: cfg.ImagesConfig{All: true}},
+			wantErr: true,
+		},
+		"ShouldThrowError_IfAllInRegistryMode": {
+			config: cfg.Configuration{
+				Images:   cfg.ImagesConfig{All: true},
+				Registry: cfg.RegistryConfig{URL: "registry.example.com"},
+			},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			config = &tt.config
+			config.Namespace = "currently-active-ns"
+			err := validateImageArguments(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedNamespace, config.Namespace)
+		})
+	}
+}
@@ -63,18 +63,19 @@ func executeNsCleanupCommand(_ *cobra.Command, _ []string) error {
 		return fmt.Errorf("cannot initiate kubernetes client: %w", err)
 	}
 
-	dynamicClient, err := kubernetes.NewDynamicClient()
+	helper, err := newKubernetesHelper()
 	if err != nil {
-		return fmt.Errorf("cannot initiate kubernetes dynamic client: %w", err)
+		return err
 	}
 
 	ctx := context.Background()
 	c := config.Resource
 	service := namespace.NewNamespacesService(
 		coreClient.Namespaces(),
-		dynamicClient,
+		helper,
 		namespace.ServiceConfiguration{
-			Batch: config.Log.Batch,
+			Batch:     config.Log.Batch,
+			Resources: extraResources,
 		})
 
 	log.Debug("Getting Namespaces")
@@ -9,7 +9,6 @@ import (
 	"github.com/appuio/seiso/cfg"
 	"github.com/appuio/seiso/pkg/cleanup"
 	"github.com/appuio/seiso/pkg/git"
-	"github.com/appuio/seiso/pkg/openshift"
 	"github.com/appuio/seiso/pkg/util"
 	"github.com/karrick/tparse/v2"
 	log "github.com/sirupsen/logrus"
@@ -21,13 +20,14 @@ const (
 This command deletes images that are not found in the git history.`
 	orphanDeletionPatternCliFlag = "deletion-pattern"
 	orphanOlderThanCliFlag       = "older-than"
+	orphanBranchPatternCliFlag   = "branch-pattern"
 )
 
 var (
 	// orphanCmd represents a cobra command to clean up images by comparing the git commit history. It removes any
 	// image tags that are not found in the git history by given criteria.
 	orphanCmd = &cobra.Command{
-		Use:          "orphans [NAMESPACE/IMAGE]",
+		Use:          "orphans [NAMESPACE/IMAGE | --all]",
 		Short:        "Clean up unknown image tags",
 		Long:         orphanCommandLongDescription,
 		Aliases:      []string{"orph", "orphan"},
@@ -43,93 +43,150 @@ func init() {
 	defaults := cfg.NewDefaultConfig()
 
 	addCommonFlagsForGit(orphanCmd, defaults)
+	addCommonFlagsForRegistry(orphanCmd, defaults)
+	addCommonFlagsForImageSelection(orphanCmd, defaults)
+	addCommonFlagsForActiveImages(orphanCmd, defaults)
+	addCommonFlagsForQuota(orphanCmd, defaults)
+	addCommonFlagsForStorageReport(orphanCmd, defaults)
 	orphanCmd.PersistentFlags().String(orphanOlderThanCliFlag, defaults.Orphan.OlderThan,
 		"Delete images that are older than the duration. Ex.: [1y2mo3w4d5h6m7s]")
 	orphanCmd.PersistentFlags().StringP(orphanDeletionPatternCliFlag, "r", defaults.Orphan.OrphanDeletionRegex,
 		"Delete images that match the regex, defaults to matching Git SHA commits")
+	orphanCmd.PersistentFlags().String(orphanBranchPatternCliFlag, defaults.Orphan.BranchPattern,
+		"Delete images of branches that do not exist anymore, instead of images of unknown commits. The regex extracts the branch "+
+			"from the image tag with the group named \"branch\" or the first group, e.g. \"^(?P<branch>.+)-[0-9a-f]{7,}$\". Replaces --deletion-pattern.")
 }
 
 func validateOrphanCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
 	defer showUsageOnError(cmd, returnErr)
-	if len(args) == 0 {
-		return missingImageNameError(config.Namespace)
+	if err := validateImageArguments(args); err != nil {
+		return err
 	}
 	c := config.Orphan
-	namespace, image, err := splitNamespaceAndImagestream(args[0])
-	if err != nil {
-		return fmt.Errorf("could not parse image name: %w", err)
-	}
 	if _, err := parseOrphanDeletionRegex(c.OrphanDeletionRegex); err != nil {
 		return fmt.Errorf("could not parse orphan deletion pattern: %w", err)
 	}
 
+	if _, err := parseBranchPattern(c.BranchPattern); err != nil {
+		return fmt.Errorf("could not parse branch pattern: %w", err)
+	}
+	if c.BranchPattern != "" && !git.HasRepository(&config.Git) {
+		return fmt.Errorf("--%s requires a git repository", orphanBranchPatternCliFlag)
+	}
+	if c.BranchPattern != "" && config.Git.RepoBranch != "" {
+		// a single branch is cloned, all other branches would be considered deleted
+		return fmt.Errorf("--%s cannot be combined with --repo-branch", orphanBranchPatternCliFlag)
+	}
+
 	if _, err := parseCutOffDateTime(c.OlderThan); err != nil {
 		return fmt.Errorf("could not parse older-than flag: %w", err)
 	}
 
-	if config.Git.Tag && !git.IsValidSortValue(config.Git.SortCriteria) {
-		return fmt.Errorf("invalid sort flag provided: %v", config.Git.SortCriteria)
+	if err := validateGitArguments(); err != nil {
+		return err
 	}
-	log.WithFields(log.Fields{
-		"namespace": namespace,
-		"image":     image,
-	}).Debug("Using image config")
-	config.Namespace = namespace
-	return nil
+	if err := validateQuotaArguments(); err != nil {
+		return err
+	}
+	return validateStorageReportArguments()
 }
 
 // ExecuteOrphanCleanupCommand executes the orphan cleanup command
 func ExecuteOrphanCleanupCommand(_ *cobra.Command, args []string) error {
 	c := config.Orphan
 	ctx := context.Background()
-	namespace, imageName, _ := splitNamespaceAndImagestream(args[0])
+	namespace := config.Namespace
 
-	allImageTags, err := openshift.GetImageStreamTags(ctx, namespace, imageName)
+	repository, err := newImageRepository(ctx, namespace)
 	if err != nil {
-		return fmt.Errorf("could not retrieve image stream '%v/%v': %w", namespace, imageName, err)
+		return err
+	}
+	imageNames, err := resolveImages(ctx, repository, args)
+	if err != nil {
+		return err
 	}
 
 	cutOffDateTime, _ := parseCutOffDateTime(c.OlderThan)
 	orphanIncludeRegex, _ := parseOrphanDeletionRegex(c.OrphanDeletionRegex)
 
-	matchOption := cleanup.MatchOptionPrefix
-	if config.Git.Tag {
-		matchOption = cleanup.MatchOptionExact
-	}
+	tagMapping, _ := newTagMapping(config.Git)
 
-	gitCandidates, err := git.GetGitCandidateList(&config.Git)
+	options := cleanup.OrphanOptions{
+		MatchOption:     newMatchOption(config.Git),
+		TagMapping:      tagMapping,
+		OlderThan:       cutOffDateTime,
+		DeletionPattern: orphanIncludeRegex,
+	}
+	gitRepository, err := openRepository(&config.Git, &config.GitAuth)
 	if err != nil {
 		return err
 	}
-	imageTagList := cleanup.FilterImageTagsByTime(&allImageTags, cutOffDateTime)
-	imageTagList = cleanup.FilterOrphanImageTags(&gitCandidates, &imageTagList, matchOption)
-	imageTagList = cleanup.FilterByRegex(&imageTagList, orphanIncludeRegex)
-	imageTagList, err = cleanup.FilterActiveImageTags(ctx, namespace, imageName, imageTagList, &imageTagList)
-	if err != nil {
+	if options.Releases, err = getReleases(&config.Git, &config.GitAuth, gitRepository); err != nil {
 		return err
 	}
-	if len(imageTagList) == 0 {
-		log.WithFields(log.Fields{
-			"\n - namespace": namespace,
-			"\n - 📺 image":   imageName,
-		}).Info("No orphaned image stream tags found")
-		return nil
+	if options.Quota, err = newQuota(ctx, namespace); err != nil {
+		return err
 	}
-
-	if config.Delete {
-		DeleteImages(ctx, imageTagList, imageName, namespace)
+	var gitCandidates []string
+	if c.BranchPattern != "" {
+		options.BranchPattern, _ = parseBranchPattern(c.BranchPattern)
+		options.DeletionPattern = nil
+		gitCandidates, err = git.GetBranchNames(gitRepository)
+		if err != nil {
+			return fmt.Errorf("retrieving branches failed: %w", err)
+		}
+		log.WithField("branches", gitCandidates).Debug("Found branches")
 	} else {
-		log.Infof("Showing results for --commit-limit=%d and --older-than=%s", config.Git.CommitLimit, c.OlderThan)
-		PrintImageTags(imageTagList, imageName, namespace)
+		gitCandidates, err = git.GetGitCandidateList(&config.Git, &config.GitAuth, gitRepository)
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
+	blobIndex := newBlobIndex(ctx, repository)
+
+	return cleanupImages(imageNames, func(imageName string) (int, error) {
+		imageTagList, err := cleanup.GetOrphanCandidates(ctx, repository, imageName, gitCandidates, options)
+		if err != nil {
+			return 0, fmt.Errorf("could not determine orphan candidates for '%s/%s': %w", namespace, imageName, err)
+		}
+		if len(imageTagList) == 0 {
+			log.WithFields(log.Fields{
+				"\n - namespace": namespace,
+				"\n - 📺 image":   imageName,
+			}).Info("No orphaned image stream tags found")
+			return 0, nil
+		}
+
+		reportStorage(ctx, blobIndex, imageName, imageTagList)
+		if config.Delete {
+			return len(imageTagList), repository.DeleteTags(ctx, imageName, imageTagList)
+		}
+		log.Infof("Showing results for --commit-limit=%d and --older-than=%s", config.Git.CommitLimit, c.OlderThan)
+		PrintImageTags(imageTagList, imageName, namespace)
+		return len(imageTagList), nil
+	})
 }
 
 func parseOrphanDeletionRegex(orphanIncludeRegex string) (*regexp.Regexp, error) {
 	return regexp.Compile(orphanIncludeRegex)
 }
 
+// parseBranchPattern compiles the pattern extracting the branch from image tags. An empty pattern returns nil.
+func parseBranchPattern(branchPattern string) (*regexp.Regexp, error) {
+	if branchPattern == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(branchPattern)
+	if err != nil {
+		return nil, err
+	}
+	if pattern.NumSubexp() == 0 {
+		return nil, fmt.Errorf("pattern %q has no group to extract the branch", branchPattern)
+	}
+	return pattern, nil
+}
+
 func parseCutOffDateTime(olderThan string) (time.Time, error) {
 	if len(olderThan) == 0 {
 		return time.Now(), nil
@@ -88,6 +88,163 @@ func Test_validateOrphanCommandInput(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "ShouldThrowError_IfBranchPatternWithoutGroup",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Orphan: cfg.OrphanConfig{
+						BranchPattern: "^.+-[0-9a-f]{7}$",
+						OlderThan:     "1w",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldAcceptBranchPattern_WithGroup",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Orphan: cfg.OrphanConfig{
+						BranchPattern: "^(.+)-[0-9a-f]{7}$",
+						OlderThan:     "1w",
+					},
+				},
+			},
+		},
+		{
+			name: "ShouldThrowError_IfBranchPatternWithoutRepository",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Git: cfg.GitConfig{
+						Forge:        "gitlab",
+						ForgeProject: "team/app",
+					},
+					Orphan: cfg.OrphanConfig{
+						BranchPattern: "^(.+)-[0-9a-f]{7}$",
+						OlderThan:     "1w",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfBranchPatternWithSingleBranchClone",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Git: cfg.GitConfig{
+						RepoURL:    "https://git.example.com/team/app.git",
+						RepoBranch: "main",
+					},
+					Orphan: cfg.OrphanConfig{
+						BranchPattern: "^(.+)-[0-9a-f]{7}$",
+						OlderThan:     "1w",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfSeveralCandidateSources",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Git: cfg.GitConfig{
+						CandidatesFile: "-",
+						RepoURL:        "https://git.example.com/team/app.git",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfInvalidForge",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Git: cfg.GitConfig{
+						Forge:        "bitbucket",
+						ForgeProject: "team/app",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfForgeWithoutProject",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Git: cfg.GitConfig{
+						Forge: "github",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfPathWithCandidatesFile",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Git: cfg.GitConfig{
+						CandidatesFile: "candidates.txt",
+						Path:           "services/api",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfProtectReleaseTagsWithCandidatesFile",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Git: cfg.GitConfig{
+						CandidatesFile:     "candidates.txt",
+						ProtectReleaseTags: true,
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfQuotaAwareInRegistryMode",
+			input: args{
+				args: []string{"team/app"},
+				config: cfg.Configuration{
+					Registry: cfg.RegistryConfig{URL: "registry.example.com"},
+					Images:   cfg.ImagesConfig{QuotaAware: true},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldThrowError_IfNegativeQuotaHeadroom",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Images: cfg.ImagesConfig{QuotaAware: true, QuotaHeadroom: -1},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ShouldAcceptForge",
+			input: args{
+				args: []string{"namespace/image"},
+				config: cfg.Configuration{
+					Git: cfg.GitConfig{
+						Forge:        "gitlab",
+						ForgeProject: "team/app",
+						Path:         "services/api",
+					},
+				},
+			},
+		},
 		{
 			name: "ShouldThrowError_IfInvalidOlderThanFlag",
 			input: args{
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/registry"
+	"github.com/spf13/cobra"
+)
+
+// addCommonFlagsForRegistry sets up the flags to use a Docker Registry HTTP API v2 instead of OpenShift ImageStreams
+func addCommonFlagsForRegistry(cmd *cobra.Command, defaults *cfg.Configuration) {
+	cmd.PersistentFlags().String("registry-url", defaults.Registry.URL,
+		"URL of a Docker Registry HTTP API v2 compatible registry. If given, the image tags are read from the registry instead of "+
+			"an OpenShift ImageStream and the image argument is the repository path in the registry, e.g. \"team/app\".")
+	cmd.PersistentFlags().String("docker-config", defaults.Registry.DockerConfig,
+		"Path to the docker config.json containing the registry credentials. Defaults to $DOCKER_CONFIG/config.json or ~/.docker/config.json")
+	cmd.PersistentFlags().Duration("registry-timeout", defaults.Registry.Timeout,
+		"Timeout of each request to the registry, e.g. \"30s\". 0 disables the timeout.")
+}
+
+// isRegistryMode returns true if the image tags are managed through a registry instead of OpenShift ImageStreams
+func isRegistryMode() bool {
+	return config.Registry.URL != ""
+}
+
+// parseImageArgument returns the namespace used to look for active images and the name of the image. In registry mode,
+// the image is the full repository path and the namespace is the one of the current context or given with --namespace.
+func parseImageArgument(arg string) (namespace string, image string, err error) {
+	if isRegistryMode() {
+		if arg == "" {
+			return "", "", fmt.Errorf("missing or invalid repository")
+		}
+		return config.Namespace, arg, nil
+	}
+	return splitNamespaceAndImagestream(arg)
+}
+
+func newRegistryClient() (*registry.Client, error) {
+	dockerConfig := config.Registry.DockerConfig
+	if dockerConfig == "" {
+		dockerConfig = registry.DefaultDockerConfigPath()
+	}
+	credentials, err := registry.LoadCredentials(dockerConfig, config.Registry.URL)
+	if err != nil {
+		return nil, fmt.Errorf("could not read registry credentials: %w", err)
+	}
+	return registry.NewClient(config.Registry.URL, credentials, config.Registry.Timeout)
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/cleanup"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	retainCommandLongDescription = `Clean up image tags by their creation time only, without a git repository.
+This is useful for images tagged with build numbers, dates or branch names. The most recently created tags are kept,
+optionally per group of tags, and tags used by resources in the cluster are never deleted.`
+)
+
+var (
+	// retainCmd represents a cobra command to clean up image tags by the tag metadata. It keeps the most recently created
+	// tags and removes the others, independent of the git history.
+	retainCmd = &cobra.Command{
+		Use:          "retain [NAMESPACE/IMAGE | --all]",
+		Short:        "Clean up image tags except the most recently created ones",
+		Long:         retainCommandLongDescription,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		PreRunE:      validateRetainCommandInput,
+		RunE:         ExecuteRetainCleanupCommand,
+	}
+)
+
+func init() {
+	imagesCmd.AddCommand(retainCmd)
+	defaults := cfg.NewDefaultConfig()
+
+	addCommonFlagsForRegistry(retainCmd, defaults)
+	addCommonFlagsForImageSelection(retainCmd, defaults)
+	addCommonFlagsForActiveImages(retainCmd, defaults)
+	retainCmd.PersistentFlags().BoolP("delete", "d", defaults.Delete, "Effectively delete image tags found")
+	retainCmd.PersistentFlags().IntP("keep", "k", defaults.History.Keep,
+		"Keep most recently created <k> image tags (per group with --group-pattern). Does not include currently used image tags.")
+	retainCmd.PersistentFlags().String("include", defaults.Retain.Include,
+		"Only consider image tags matching the regex, e.g. \"^build-[0-9]+$\"")
+	retainCmd.PersistentFlags().String("exclude", defaults.Retain.Exclude,
+		"Never delete image tags matching the regex, e.g. \"^(latest|stable)$\"")
+	retainCmd.PersistentFlags().String("group-pattern", defaults.Retain.GroupPattern,
+		"Keep <k> image tags per group, grouped by the first capture group of the regex, e.g. \"^(.+)-[0-9]+$\" for one group per branch prefix")
+}
+
+func validateRetainCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
+	defer showUsageOnError(cmd, returnErr)
+	if err := validateImageArguments(args); err != nil {
+		return err
+	}
+	if _, err := parseRetainOptions(config.Retain, config.History.Keep); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ExecuteRetainCleanupCommand executes the retain cleanup command
+func ExecuteRetainCleanupCommand(_ *cobra.Command, args []string) error {
+	ctx := context.Background()
+	namespace := config.Namespace
+
+	repository, err := newImageRepository(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	imageNames, err := resolveImages(ctx, repository, args)
+	if err != nil {
+		return err
+	}
+	options, err := parseRetainOptions(config.Retain, config.History.Keep)
+	if err != nil {
+		return err
+	}
+
+	return cleanupImages(imageNames, func(imageName string) (int, error) {
+		candidates, err := cleanup.GetRetainCandidates(ctx, repository, imageName, options)
+		if err != nil {
+			return 0, fmt.Errorf("could not determine retain candidates for '%s/%s': %w", namespace, imageName, err)
+		}
+		if len(candidates) == 0 {
+			log.WithFields(log.Fields{
+				"\n - namespace": namespace,
+				"\n - 📺 image":   imageName,
+			}).Info("No image tags to clean up found")
+			return 0, nil
+		}
+		if config.Delete {
+			return len(candidates), repository.DeleteTags(ctx, imageName, candidates)
+		}
+		log.Infof("Showing results for --keep=%d", config.History.Keep)
+		PrintImageTags(candidates, imageName, namespace)
+		return len(candidates), nil
+	})
+}
+
+// parseRetainOptions compiles the patterns of the retain configuration
+func parseRetainOptions(c cfg.RetainConfig, keep int) (cleanup.RetainOptions, error) {
+	options := cleanup.RetainOptions{Keep: keep}
+	if keep < 0 {
+		return options, fmt.Errorf("keep flag must not be negative: %d", keep)
+	}
+	patterns := []struct {
+		flag    string
+		value   string
+		pattern **regexp.Regexp
+	}{
+		{flag: "include", value: c.Include, pattern: &options.Include},
+		{flag: "exclude", value: c.Exclude, pattern: &options.Exclude},
+		{flag: "group-pattern", value: c.GroupPattern, pattern: &options.GroupPattern},
+	}
+	for _, p := range patterns {
+		if p.value == "" {
+			continue
+		}
+		compiled, err := regexp.Compile(p.value)
+		if err != nil {
+			return options, fmt.Errorf("could not parse %s flag: %w", p.flag, err)
+		}
+		*p.pattern = compiled
+	}
+	return options, nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateRetainCommandInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		config  cfg.Configuration
+		wantErr bool
+	}{
+		{
+			name: "ShouldAcceptPatterns",
+			args: []string{"namespace/image"},
+			config: cfg.Configuration{
+				History: cfg.HistoryConfig{Keep: 2},
+				Retain:  cfg.RetainConfig{Include: "^build-", Exclude: "^latest$", GroupPattern: "^(.+)-[0-9]+$"},
+			},
+		},
+		{
+			name:    "ShouldThrowError_IfMissingImage",
+			wantErr: true,
+		},
+		{
+			name:    "ShouldThrowError_IfInvalidIncludePattern",
+			args:    []string{"namespace/image"},
+			config:  cfg.Configuration{Retain: cfg.RetainConfig{Include: "*/g"}},
+			wantErr: true,
+		},
+		{
+			name:    "ShouldThrowError_IfInvalidGroupPattern",
+			args:    []string{"namespace/image"},
+			config:  cfg.Configuration{Retain: cfg.RetainConfig{GroupPattern: "(unclosed"}},
+			wantErr: true,
+		},
+		{
+			name:    "ShouldThrowError_IfNegativeKeep",
+			args:    []string{"namespace/image"},
+			config:  cfg.Configuration{History: cfg.HistoryConfig{Keep: -1}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config = &tt.config
+			err := validateRetainCommandInput(&cobra.Command{}, tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"github.com/appuio/seiso/pkg/kubernetes"
+	"github.com/appuio/seiso/pkg/openshift"
 	"os"
 	"strings"
 
@@ -13,18 +14,22 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var (
 	// rootCmd represents the base command when called without any subcommands
 	rootCmd = &cobra.Command{
-		Use:               "seiso",
-		Short:             "Keeps your Kubernetes projects clean",
-		PersistentPreRunE: parseConfig,
+		Use:                "seiso",
+		Short:              "Keeps your Kubernetes projects clean",
+		PersistentPreRunE:  parseConfig,
+		PersistentPostRunE: saveSnapshot,
 	}
 	config        = cfg.NewDefaultConfig()
 	koanfInstance = koanf.New(".")
 	version       = "undefined"
+	// extraResources are the parsed --extra-resources
+	extraResources []schema.GroupVersionResource
 )
 
 // Execute is the main entrypoint of the CLI, it executes child commands as given by the user-defined flags and arguments.
@@ -38,6 +43,15 @@ func init() {
 	rootCmd.PersistentFlags().BoolP("log.verbose", "v", config.Log.Verbose, "Shorthand for \"--log.level debug\"")
 	rootCmd.PersistentFlags().BoolP("log.batch", "b", config.Log.Batch,
 		"Use Batch mode (Prints error to StdErr, StdOut is used to just print resource names, useful for piping)")
+	rootCmd.PersistentFlags().String("snapshot-file", config.Snapshot.File,
+		"File to save the listed cluster resources to, so that following commands within --snapshot-max-age reuse them instead of listing them again")
+	rootCmd.PersistentFlags().String("snapshot-max-age", config.Snapshot.MaxAge,
+		"Maximum age of the resources in --snapshot-file, older snapshots are discarded. Format: a duration like 10m, 1h or 1d")
+	rootCmd.PersistentFlags().StringSlice("extra-resources", config.Discovery.ExtraResources,
+		"Additional resources that are searched for references, e.g. custom resources. Format: resource.version.group or resource.group, e.g. \"rollouts.v1alpha1.argoproj.io\"")
+	rootCmd.PersistentFlags().String("reference-mode", config.Discovery.ReferenceMode,
+		fmt.Sprintf("How references to images, ConfigMaps and Secrets are detected. '%s' reads the fields that reference them, '%s' searches all fields of the resources for the name.",
+			openshift.ReferenceModeStructured, openshift.ReferenceModeSubstring))
 	cobra.OnInitialize(initRootConfig)
 }
 
@@ -75,6 +89,9 @@ func parseConfig(cmd *cobra.Command, args []string) error {
 	} else {
 		log.SetLevel(level)
 	}
+	if extraResources, err = validateDiscoveryArguments(config.Discovery); err != nil {
+		return err
+	}
 	if config.Namespace == "" {
 		namespace, err := kubernetes.Namespace()
 		if err != nil {
@@ -125,3 +142,25 @@ func SetVersion(v string) {
 	rootCmd.Version = v
 	version = v
 }
+
+// validateDiscoveryArguments validates the reference mode and returns the parsed extra resources
+func validateDiscoveryArguments(c cfg.DiscoveryConfig) ([]schema.GroupVersionResource, error) {
+	if !openshift.IsValidReferenceMode(c.ReferenceMode) {
+		return nil, fmt.Errorf("unknown reference mode %q, use '%s' or '%s'", c.ReferenceMode,
+			openshift.ReferenceModeStructured, openshift.ReferenceModeSubstring)
+	}
+	return parseExtraResources(c.ExtraResources)
+}
+
+// parseExtraResources parses the additional resources to search for references
+func parseExtraResources(values []string) ([]schema.GroupVersionResource, error) {
+	resources := make([]schema.GroupVersionResource, 0, len(values))
+	for _, value := range values {
+		resource, err := kubernetes.ParseResource(value)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse extra-resources flag: %w", err)
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
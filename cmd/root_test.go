@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/openshift"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateDiscoveryArguments(t *testing.T) {
+	tests := map[string]struct {
+		config  cfg.DiscoveryConfig
+		wantErr bool
+	}{
+		"ShouldAcceptStructuredReferenceMode": {
+			config: cfg.DiscoveryConfig{ReferenceMode: openshift.ReferenceModeStructured},
+		},
+		"ShouldAcceptSubstringReferenceMode": {
+			config: cfg.DiscoveryConfig{ReferenceMode: openshift.ReferenceModeSubstring},
+		},
+		"ShouldThrowError_IfReferenceModeIsUnknown": {
+			config:  cfg.DiscoveryConfig{ReferenceMode: "fuzzy"},
+			wantErr: true,
+		},
+		"ShouldThrowError_IfExtraResourceIsInvalid": {
+			config:  cfg.DiscoveryConfig{ReferenceMode: openshift.ReferenceModeStructured, ExtraResources: []string{""}},
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := validateDiscoveryArguments(tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
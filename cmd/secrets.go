@@ -8,6 +8,7 @@ import (
 	"github.com/appuio/seiso/cfg"
 	"github.com/appuio/seiso/pkg/kubernetes"
 	"github.com/appuio/seiso/pkg/secret"
+	"github.com/appuio/seiso/pkg/util"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -40,6 +41,8 @@ func init() {
 		"Keep most current <k> Secrets; does not include currently used secret (if detected)")
 	secretCmd.PersistentFlags().String("older-than", defaults.Resource.OlderThan,
 		"Delete Secrets that are older than the duration, e.g. [1y2mo3w4d5h6m7s]")
+	secretCmd.PersistentFlags().String("group-by", defaults.Resource.GroupBy,
+		"Apply --keep and --older-than per group of Secrets: \"kustomize\" (name without hash suffix) or \"label:<key>\"")
 }
 
 func validateSecretCommandInput(cmd *cobra.Command, args []string) (returnErr error) {
@@ -55,6 +58,9 @@ func validateSecretCommandInput(cmd *cobra.Command, args []string) (returnErr er
 	if _, err := parseCutOffDateTime(config.Resource.OlderThan); err != nil {
 		return fmt.Errorf("could not parse older-than flag: %w", err)
 	}
+	if err := util.ValidateGroupBy(config.Resource.GroupBy); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -64,13 +70,22 @@ func executeSecretCleanupCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("cannot initiate kubernetes client: %w", err)
 	}
 
+	helper, err := newKubernetesHelper()
+	if err != nil {
+		return err
+	}
+
 	ctx := context.Background()
 	c := config.Resource
 	namespace := config.Namespace
 	service := secret.NewSecretsService(
 		coreClient.Secrets(namespace),
-		kubernetes.New(),
-		secret.ServiceConfiguration{Batch: config.Log.Batch})
+		helper,
+		secret.ServiceConfiguration{
+			Batch:         config.Log.Batch,
+			Resources:     extraResources,
+			ReferenceMode: config.Discovery.ReferenceMode,
+		})
 
 	log.WithField("namespace", namespace).Debug("Getting Secrets")
 	foundSecrets, err := service.List(ctx, toListOptions(c.Labels))
@@ -85,8 +100,7 @@ func executeSecretCleanupCommand(cmd *cobra.Command, args []string) error {
 
 	cutOffDateTime, _ := parseCutOffDateTime(c.OlderThan)
 
-	filteredSecrets := service.FilterByTime(unusedSecrets, cutOffDateTime)
-	filteredSecrets = service.FilterByMaxCount(filteredSecrets, config.History.Keep)
+	filteredSecrets := service.FilterPerGroup(unusedSecrets, c.GroupBy, cutOffDateTime, config.History.Keep)
 
 	if config.Delete {
 		err := service.Delete(ctx, filteredSecrets)
@@ -98,6 +112,7 @@ func executeSecretCleanupCommand(cmd *cobra.Command, args []string) error {
 			"namespace":  namespace,
 			"keep":       config.History.Keep,
 			"older_than": c.OlderThan,
+			"group_by":   c.GroupBy,
 		}).Info("Showing results")
 		service.Print(filteredSecrets)
 	}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/appuio/seiso/pkg/kubernetes"
+	"github.com/karrick/tparse/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// snapshot holds the cluster resources listed by the current command, it is shared by all usage checks
+var snapshot *kubernetes.Snapshot
+
+// newKubernetesHelper creates the helper to check the usage of resources. All helpers share one snapshot, which is
+// loaded from --snapshot-file if given.
+func newKubernetesHelper() (kubernetes.Kubernetes, error) {
+	if snapshot != nil {
+		return kubernetes.NewWithSnapshot(snapshot), nil
+	}
+	if config.Snapshot.File == "" {
+		snapshot = kubernetes.NewSnapshot()
+		return kubernetes.NewWithSnapshot(snapshot), nil
+	}
+	maxAge, err := parseMaxAge(config.Snapshot.MaxAge)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse snapshot-max-age flag: %w", err)
+	}
+	loaded, err := kubernetes.LoadSnapshot(config.Snapshot.File, maxAge)
+	if err != nil {
+		return nil, fmt.Errorf("could not load snapshot: %w", err)
+	}
+	log.WithFields(log.Fields{
+		"file":    config.Snapshot.File,
+		"created": loaded.Created(),
+	}).Debug("Using snapshot")
+	snapshot = loaded
+	return kubernetes.NewWithSnapshot(snapshot), nil
+}
+
+// saveSnapshot writes the resources listed by the command to --snapshot-file, so that following commands reuse them
+func saveSnapshot(_ *cobra.Command, _ []string) error {
+	if config.Snapshot.File == "" || snapshot == nil {
+		return nil
+	}
+	if err := snapshot.Save(config.Snapshot.File); err != nil {
+		return fmt.Errorf("could not save snapshot: %w", err)
+	}
+	return nil
+}
+
+// parseMaxAge parses the maximum age of a snapshot like --older-than, e.g. "10m" or "1d"
+func parseMaxAge(maxAge string) (time.Duration, error) {
+	now := time.Now()
+	notAfter, err := tparse.AddDuration(now, maxAge)
+	if err != nil {
+		return 0, err
+	}
+	return notAfter.Sub(now), nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseMaxAge(t *testing.T) {
+	tests := map[string]struct {
+		maxAge   string
+		expected time.Duration
+		wantErr  bool
+	}{
+		"ShouldParseMinutes": {
+			maxAge:   "10m",
+			expected: 10 * time.Minute,
+		},
+		"ShouldParseDays": {
+			maxAge:   "1d",
+			expected: 24 * time.Hour,
+		},
+		"ShouldParseCombinedUnits": {
+			maxAge:   "1h30m",
+			expected: 90 * time.Minute,
+		},
+		"ShouldThrowError_IfMaxAgeIsInvalid": {
+			maxAge:  "soon",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			maxAge, err := parseMaxAge(tt.maxAge)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, maxAge)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package cleanup
+
+import (
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// companionTagPattern matches the tags of cosign signatures, attestations and SBOMs ("sha256-<hex>.sig", ".att",
+// ".sbom") and of the OCI referrers tag schema ("sha256-<hex>")
+var companionTagPattern = regexp.MustCompile(`^sha256-([a-f0-9]{64})(\.[a-z]+)?$`)
+
+// CompanionParent returns the digest of the image that the companion tag belongs to, or false if the tag is not a
+// companion tag
+func CompanionParent(tag string) (string, bool) {
+	match := companionTagPattern.FindStringSubmatch(tag)
+	if match == nil {
+		return "", false
+	}
+	return "sha256:" + match[1], true
+}
+
+// FilterCompanionTags removes the companion tags whose parent image is in the history of a tag that is kept from the
+// candidates, and adds the companion tags whose parent image is deleted with the candidates. Other companion tags are
+// left to the rules that selected the candidates, e.g. the companions of images that are only deployed by digest.
+func FilterCompanionTags(imageTags []ImageTag, candidates *[]string) []string {
+	filtered, added := filterCompanionTags(imageTags, *candidates)
+	for _, tag := range added {
+		log.WithField("tag", tag).Debug("Deleting companion tag of deleted image")
+	}
+	return append(filtered, added...)
+}
+
+// filterCompanionTags returns the candidates without the companion tags of kept images, and separately the companion
+// tags added for the deleted images
+func filterCompanionTags(imageTags []ImageTag, candidates []string) ([]string, []string) {
+	isCandidate := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		isCandidate[candidate] = true
+	}
+	keptImages := map[string]bool{}
+	companions := map[string]ImageTag{}
+	for _, imageTag := range imageTags {
+		if _, isCompanion := CompanionParent(imageTag.Name); isCompanion {
+			companions[imageTag.Name] = imageTag
+			continue
+		}
+		if !isCandidate[imageTag.Name] {
+			addImages(keptImages, imageTag, nil)
+		}
+	}
+	// companions of kept images are kept, as well as their own companions, e.g. the signature of an attestation
+	keptCompanions := selectCompanions(companions, keptImages, keptImages, nil)
+
+	deletedImages := map[string]bool{}
+	for _, imageTag := range imageTags {
+		if isCandidate[imageTag.Name] && !keptCompanions[imageTag.Name] {
+			addImages(deletedImages, imageTag, keptImages)
+		}
+	}
+	deletedCompanions := selectCompanions(companions, deletedImages, deletedImages, keptImages)
+
+	var filtered []string
+	for _, candidate := range candidates {
+		if !keptCompanions[candidate] && !deletedCompanions[candidate] {
+			filtered = append(filtered, candidate)
+		}
+	}
+	var added []string
+	for _, imageTag := range imageTags {
+		if deletedCompanions[imageTag.Name] {
+			added = append(added, imageTag.Name)
+		}
+	}
+	return filtered, added
+}
+
+// selectCompanions returns the companion tags whose parent is one of the parent images. The images of the selected
+// companions are added to the images, except the excluded ones, so that their own companions are selected too.
+func selectCompanions(companions map[string]ImageTag, parents, images, excluded map[string]bool) map[string]bool {
+	selected := map[string]bool{}
+	for found := true; found; {
+		found = false
+		for tag, imageTag := range companions {
+			if parent, _ := CompanionParent(tag); selected[tag] || !parents[parent] {
+				continue
+			}
+			selected[tag] = true
+			addImages(images, imageTag, excluded)
+			found = true
+		}
+	}
+	return selected
+}
+
+// addImages adds the images in the history of the tag to the images, except the excluded ones
+func addImages(images map[string]bool, imageTag ImageTag, excluded map[string]bool) {
+	for _, digest := range imageTag.Digests {
+		if !excluded[digest] {
+			images[digest] = true
+		}
+	}
+}
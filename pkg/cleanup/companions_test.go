@@ -0,0 +1,73 @@
+package cleanup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func digestOf(name string) string {
+	return "sha256:" + strings.Repeat(name, 64/len(name))
+}
+
+func companionOf(name, suffix string) string {
+	return "sha256-" + strings.TrimPrefix(digestOf(name), "sha256:") + suffix
+}
+
+func newCompanionTestTags(images map[string]string) []ImageTag {
+	var imageTags []ImageTag
+	for tag, image := range images {
+		imageTags = append(imageTags, ImageTag{Name: tag, Digests: []string{image}})
+	}
+	return imageTags
+}
+
+func Test_CompanionParent(t *testing.T) {
+	tests := map[string]struct {
+		tag            string
+		expectedParent string
+		expectedFound  bool
+	}{
+		"GivenSignature_ThenReturnParent":     {tag: companionOf("a", ".sig"), expectedParent: digestOf("a"), expectedFound: true},
+		"GivenAttestation_ThenReturnParent":   {tag: companionOf("b", ".att"), expectedParent: digestOf("b"), expectedFound: true},
+		"GivenSBOM_ThenReturnParent":          {tag: companionOf("c", ".sbom"), expectedParent: digestOf("c"), expectedFound: true},
+		"GivenReferrersTag_ThenReturnParent":  {tag: companionOf("d", ""), expectedParent: digestOf("d"), expectedFound: true},
+		"GivenCommitTag_ThenReturnFalse":      {tag: strings.Repeat("a", 40)},
+		"GivenShortDigestTag_ThenReturnFalse": {tag: "sha256-abc.sig"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			parent, found := CompanionParent(tt.tag)
+
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expectedParent, parent)
+		})
+	}
+}
+
+func Test_FilterCompanionTags(t *testing.T) {
+	imageTags := newCompanionTestTags(map[string]string{
+		"kept":    digestOf("a"),
+		"deleted": digestOf("b"),
+		// the image is deleted with "deleted", but kept with "kept"
+		"shared":                  digestOf("a"),
+		companionOf("a", ".sig"):  digestOf("c"),
+		companionOf("a", ".att"):  digestOf("e"),
+		companionOf("e", ".sig"):  digestOf("f"),
+		companionOf("b", ".sig"):  digestOf("1"),
+		companionOf("b", ".att"):  digestOf("2"),
+		companionOf("2", ".sig"):  digestOf("3"),
+		companionOf("9", ".sig"):  digestOf("4"),
+		companionOf("8", ".sbom"): digestOf("5"),
+	})
+	// the parents of the companions of "9" and "8" have no tag, e.g. they were deleted before or are deployed by digest
+	candidates := []string{"deleted", "shared", companionOf("a", ".sig"), companionOf("8", ".sbom")}
+
+	filtered := FilterCompanionTags(imageTags, &candidates)
+
+	assert.ElementsMatch(t, []string{
+		"deleted", "shared", companionOf("8", ".sbom"),
+		companionOf("b", ".sig"), companionOf("b", ".att"), companionOf("2", ".sig"),
+	}, filtered)
+}
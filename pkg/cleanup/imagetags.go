@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/appuio/seiso/pkg/openshift"
-	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/appuio/seiso/pkg/git"
 	log "github.com/sirupsen/logrus"
 	"github.com/thoas/go-funk"
 )
@@ -23,9 +23,11 @@ const (
 	MatchOptionPrefix MatchOption = "prefix"
 )
 
-// GetMatchingTags returns all image tags matching one of the provided git tags
-func GetMatchingTags(gitTags, imageTags *[]string, matchOption MatchOption) []string {
+// GetMatchingTags returns all image tags matching one of the provided git tags, after mapping the image tags. Each
+// image tag is returned once, in the order of the first git tag it matches.
+func GetMatchingTags(gitTags, imageTags *[]string, matchOption MatchOption, mapping TagMapping) []string {
 	var matchingTags []string
+	matched := map[string]bool{}
 
 	log.WithFields(log.Fields{
 		"match":     matchOption,
@@ -39,7 +41,8 @@ func GetMatchingTags(gitTags, imageTags *[]string, matchOption MatchOption) []st
 
 	for _, gitTag := range *gitTags {
 		for _, imageTag := range *imageTags {
-			if match(imageTag, gitTag, matchOption) {
+			if !matched[imageTag] && mapping.Matches(imageTag, gitTag, matchOption) {
+				matched[imageTag] = true
 				matchingTags = append(matchingTags, imageTag)
 				log.WithFields(log.Fields{
 					"gitTag":   gitTag,
@@ -60,8 +63,9 @@ func GetInactiveImageTags(activeTags, allImageTags *[]string) []string {
 	return inactiveTags
 }
 
-// FilterOrphanImageTags returns the tags that do not have any git commit match
-func FilterOrphanImageTags(gitValues, imageTags *[]string, matchOption MatchOption) []string {
+// FilterOrphanImageTags returns the tags that do not have any git commit match. With a mapping pattern, tags not
+// matching the pattern are not returned, since they cannot be compared.
+func FilterOrphanImageTags(gitValues, imageTags *[]string, matchOption MatchOption, mapping TagMapping) []string {
 
 	log.WithFields(log.Fields{
 		"imageTagsToFilter": imageTags,
@@ -69,8 +73,11 @@ func FilterOrphanImageTags(gitValues, imageTags *[]string, matchOption MatchOpti
 	}).Debug("Filtering image tags by commits...")
 
 	orphans := funk.FilterString(*imageTags, func(imageTag string) bool {
+		if _, found := mapping.Extract(imageTag); !found {
+			return false
+		}
 		for _, gitValue := range *gitValues {
-			if match(imageTag, gitValue, matchOption) {
+			if mapping.Matches(imageTag, gitValue, matchOption) {
 				return false
 			}
 		}
@@ -79,6 +86,65 @@ func FilterOrphanImageTags(gitValues, imageTags *[]string, matchOption MatchOpti
 	return orphans
 }
 
+// FilterReleaseTags returns the tags that neither match the commit of a release nor the name of a release. The releases
+// map git tag names to their commit. Commits are compared by prefix like in the commit history, using the mapping.
+func FilterReleaseTags(releases map[string]string, imageTags *[]string, mapping TagMapping) []string {
+	return funk.FilterString(*imageTags, func(imageTag string) bool {
+		for name, commit := range releases {
+			if mapping.Matches(imageTag, commit, MatchOptionPrefix) ||
+				imageTag == name || imageTag == strings.TrimPrefix(name, mapping.StripPrefix) {
+				log.WithFields(log.Fields{
+					"release":  name,
+					"imageTag": imageTag,
+				}).Debug("Keeping image tag of release")
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// FilterVanishedBranchTags returns the tags matching the branch pattern whose branch does not exist anymore. The
+// branch of a tag is compared with the branch names and their slugs (see git.BranchSlug). Tags not matching the
+// pattern are not returned.
+func FilterVanishedBranchTags(branches, imageTags *[]string, branchPattern *regexp.Regexp) []string {
+	knownBranches := map[string]bool{}
+	for _, branch := range *branches {
+		knownBranches[branch] = true
+		knownBranches[git.BranchSlug(branch)] = true
+	}
+
+	var vanishedTags []string
+	for _, tag := range *imageTags {
+		branch, found := TagBranch(tag, branchPattern)
+		if !found {
+			continue
+		}
+		log.WithFields(log.Fields{
+			"imageTag": tag,
+			"branch":   branch,
+			"exists":   knownBranches[branch],
+		}).Debug("Matching image tag with branches")
+		if !knownBranches[branch] {
+			vanishedTags = append(vanishedTags, tag)
+		}
+	}
+	return vanishedTags
+}
+
+// TagBranch returns the branch of the tag, which is the capture group named "branch" or otherwise the first capture
+// group of the pattern. The second value is false if the tag does not match the pattern.
+func TagBranch(tag string, branchPattern *regexp.Regexp) (string, bool) {
+	match := branchPattern.FindStringSubmatch(tag)
+	if len(match) < 2 {
+		return "", false
+	}
+	if i := branchPattern.SubexpIndex("branch"); i > 0 {
+		return match[i], match[i] != ""
+	}
+	return match[1], match[1] != ""
+}
+
 // FilterByRegex returns the tags that match the regexp
 func FilterByRegex(imageTags *[]string, regexp *regexp.Regexp) []string {
 	var matchedTags []string
@@ -110,25 +176,49 @@ func LimitTags(tags *[]string, keep int) []string {
 }
 
 // FilterImageTagsByTime returns the tags which are older than the specified time
-func FilterImageTagsByTime(imageStreamObjectTags *[]imagev1.NamedTagEventList, olderThan time.Time) []string {
+func FilterImageTagsByTime(imageStreamObjectTags *[]ImageTag, olderThan time.Time) []string {
 	var imageStreamTags []string
 
 	for _, imageStreamTag := range *imageStreamObjectTags {
-		lastUpdatedDate := imageStreamTag.Items[0].Created.Time
-		for _, tagEvent := range imageStreamTag.Items {
-			if lastUpdatedDate.Before(tagEvent.Created.Time) {
-				lastUpdatedDate = tagEvent.Created.Time
-			}
-		}
-
-		if lastUpdatedDate.Before(olderThan) {
-			imageStreamTags = append(imageStreamTags, imageStreamTag.Tag)
+		if imageStreamTag.Created.Before(olderThan) {
+			imageStreamTags = append(imageStreamTags, imageStreamTag.Name)
 		}
 	}
 
 	return imageStreamTags
 }
 
+// SortImageTagsByCreation sorts the tags by the time they were last updated, newest first
+func SortImageTagsByCreation(imageStreamTags []ImageTag) {
+	sort.SliceStable(imageStreamTags, func(i, j int) bool {
+		return imageStreamTags[j].Created.Before(imageStreamTags[i].Created)
+	})
+}
+
+// GroupTagsByPattern groups the tags by the first capture group of the pattern, or the whole match if the pattern has
+// no capture group. Tags not matching the pattern are in the empty group, as are all tags without pattern. The group
+// names are returned sorted, the order of the tags within a group is preserved.
+func GroupTagsByPattern(tags []string, pattern *regexp.Regexp) ([]string, map[string][]string) {
+	groupTags := map[string][]string{}
+	for _, tag := range tags {
+		group := ""
+		if pattern != nil {
+			if match := pattern.FindStringSubmatch(tag); len(match) > 1 {
+				group = match[1]
+			} else if len(match) == 1 {
+				group = match[0]
+			}
+		}
+		groupTags[group] = append(groupTags[group], tag)
+	}
+	groups := make([]string, 0, len(groupTags))
+	for group := range groupTags {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups, groupTags
+}
+
 func match(imageTag, value string, matchOption MatchOption) bool {
 	switch matchOption {
 	case MatchOptionPrefix:
@@ -140,10 +230,10 @@ func match(imageTag, value string, matchOption MatchOption) bool {
 }
 
 // FilterActiveImageTags first gets all actively used image tags from imageStreamTags, then filters them out from matchingTags
-func FilterActiveImageTags(ctx context.Context, namespace string, imageName string, imageStreamTags []string, matchingTags *[]string) ([]string, error) {
-	activeImageStreamTags, err := openshift.GetActiveImageStreamTags(ctx, namespace, imageName, imageStreamTags)
+func FilterActiveImageTags(ctx context.Context, repository ImageRepository, imageName string, imageStreamTags []string, matchingTags *[]string) ([]string, error) {
+	activeImageStreamTags, err := repository.GetActiveTags(ctx, imageName, imageStreamTags)
 	if err != nil {
-		return nil, fmt.Errorf("could not retrieve active image tags from %v/%v': %w", namespace, imageName, err)
+		return nil, fmt.Errorf("could not retrieve active image tags from '%v': %w", imageName, err)
 	}
 
 	log.WithField("activeTags", activeImageStreamTags).Debug("Found currently active image tags")
@@ -5,9 +5,7 @@ import (
 	"testing"
 	"time"
 
-	imagev1 "github.com/openshift/api/image/v1"
 	"github.com/stretchr/testify/assert"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type GetMatchingTagsTestCase struct {
@@ -34,7 +32,7 @@ type FilterByRegexTestCase struct {
 }
 
 type TagsOlderThanTestCase struct {
-	tags      []imagev1.NamedTagEventList
+	tags      []ImageTag
 	expected  []string
 	olderThan time.Time
 }
@@ -66,10 +64,24 @@ func Test_GetMatchingTags(t *testing.T) {
 				"c8a693ad89e7069674eda512c553ff56d3ca2ffd-debug",
 			},
 		},
+		{
+			// the image tag matches the abbreviated and the full commit hash, but is returned once
+			matchOption: MatchOptionPrefix,
+			matchValues: []string{
+				"0b81a958",
+				"0b81a958f590ed7ed8be6ec0a2a87816228a482c",
+			},
+			tags: []string{
+				"0b81a958f590ed7ed8be6ec0a2a87816228a482c",
+			},
+			expected: []string{
+				"0b81a958f590ed7ed8be6ec0a2a87816228a482c",
+			},
+		},
 	}
 
 	for _, testcase := range testcases {
-		assert.Equal(t, testcase.expected, GetMatchingTags(&testcase.matchValues, &testcase.tags, testcase.matchOption))
+		assert.Equal(t, testcase.expected, GetMatchingTags(&testcase.matchValues, &testcase.tags, testcase.matchOption, TagMapping{}))
 	}
 }
 
@@ -160,7 +172,7 @@ func Test_GetOrphanTags(t *testing.T) {
 	}
 
 	for _, testcase := range testcases {
-		assert.Equal(t, testcase.expected, FilterOrphanImageTags(&testcase.matchValues, &testcase.tags, testcase.matchOption))
+		assert.Equal(t, testcase.expected, FilterOrphanImageTags(&testcase.matchValues, &testcase.tags, testcase.matchOption, TagMapping{}))
 	}
 }
 
@@ -225,6 +237,72 @@ func Test_FilterByRegex(t *testing.T) {
 	}
 }
 
+func Test_FilterVanishedBranchTags(t *testing.T) {
+	branches := []string{"master", "feature/Login_Form", "release/1.0"}
+	tags := []string{
+		"master-1a2b3c4",
+		"feature-login-form-5d6e7f8",
+		"feature-payment-9a8b7c6",
+		"release-1-0-1234567",
+		"hotfix-7654321",
+		"latest",
+	}
+	tests := map[string]struct {
+		pattern  *regexp.Regexp
+		expected []string
+	}{
+		"ShouldUseFirstGroup": {
+			pattern:  regexp.MustCompile("^(.+)-[0-9a-f]{7}$"),
+			expected: []string{"feature-payment-9a8b7c6", "hotfix-7654321"},
+		},
+		"ShouldPreferNamedGroup": {
+			pattern:  regexp.MustCompile("^(?:build-)?(?P<branch>.+)-([0-9a-f]{7})$"),
+			expected: []string{"feature-payment-9a8b7c6", "hotfix-7654321"},
+		},
+		"ShouldIgnoreTagsNotMatchingPattern": {
+			pattern:  regexp.MustCompile("^(feature-.+)-[0-9a-f]{7}$"),
+			expected: []string{"feature-payment-9a8b7c6"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FilterVanishedBranchTags(&branches, &tags, tt.pattern))
+		})
+	}
+}
+
+func Test_FilterReleaseTags(t *testing.T) {
+	releases := map[string]string{
+		"v1.0.0": "1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d",
+		"v1.1.0": "9f8e7d6c5b4a39281706f5e4d3c2b1a098f7e6d5",
+	}
+	tags := []string{
+		"1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d",
+		"main-9f8e7d6",
+		"main-5d6e7f8",
+		"v1.0.0",
+		"1.1.0",
+		"latest",
+	}
+	tests := map[string]struct {
+		mapping  TagMapping
+		expected []string
+	}{
+		"ShouldKeepTagsOfReleaseCommitsAndNames": {
+			expected: []string{"main-9f8e7d6", "main-5d6e7f8", "1.1.0", "latest"},
+		},
+		"ShouldKeepTagsOfReleaseCommits_WithMapping": {
+			mapping:  TagMapping{Pattern: regexp.MustCompile("^main-([0-9a-f]{7})$"), Group: TagMappingGroupSha, StripPrefix: "v"},
+			expected: []string{"1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d", "main-5d6e7f8", "latest"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FilterReleaseTags(releases, &tags, tt.mapping))
+		})
+	}
+}
+
 func Test_LimitTags(t *testing.T) {
 	testcases := []LimitTagsTestCase{
 		{
@@ -266,51 +344,22 @@ func Test_LimitTags(t *testing.T) {
 func Test_TagsOlderThan(t *testing.T) {
 	testcases := []TagsOlderThanTestCase{
 		{
-			tags: []imagev1.NamedTagEventList{
+			tags: []ImageTag{
 				{
-					Tag: "0b81a958f590ed7ed8be6ec0a2a87816228a482c",
-					Items: []imagev1.TagEvent{
-						{
-							Created: metav1.Time{
-								Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local),
-							},
-						},
-						{
-							Created: metav1.Time{
-								Time: time.Date(2020, 5, 5, 0, 0, 0, 0, time.Local),
-							},
-						},
-					},
+					Name:    "0b81a958f590ed7ed8be6ec0a2a87816228a482c",
+					Created: time.Date(2020, 5, 5, 0, 0, 0, 0, time.Local),
 				},
 				{
-					Tag: "108f2be974f8e1e5fec8bc759ecf824e81565747",
-					Items: []imagev1.TagEvent{
-						{
-							Created: metav1.Time{
-								Time: time.Date(2020, 4, 4, 0, 0, 0, 0, time.Local),
-							},
-						},
-					},
+					Name:    "108f2be974f8e1e5fec8bc759ecf824e81565747",
+					Created: time.Date(2020, 4, 4, 0, 0, 0, 0, time.Local),
 				},
 				{
-					Tag: "4cb7de27c985216b8888ff6049294dae02f3282e",
-					Items: []imagev1.TagEvent{
-						{
-							Created: metav1.Time{
-								Time: time.Date(2020, 3, 3, 0, 0, 0, 0, time.Local),
-							},
-						},
-					},
+					Name:    "4cb7de27c985216b8888ff6049294dae02f3282e",
+					Created: time.Date(2020, 3, 3, 0, 0, 0, 0, time.Local),
 				},
 				{
-					Tag: "c8a693ad89e7069674eda512c553ff56d3ca2ffd-debug",
-					Items: []imagev1.TagEvent{
-						{
-							Created: metav1.Time{
-								Time: time.Date(2020, 2, 2, 0, 0, 0, 0, time.Local),
-							},
-						},
-					},
+					Name:    "c8a693ad89e7069674eda512c553ff56d3ca2ffd-debug",
+					Created: time.Date(2020, 2, 2, 0, 0, 0, 0, time.Local),
 				},
 			},
 			expected: []string{
@@ -418,7 +467,7 @@ func TestFilterOrphanImageTags(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := FilterOrphanImageTags(tt.args.gitValues, tt.args.imageTags, tt.args.matchOption)
+			result := FilterOrphanImageTags(tt.args.gitValues, tt.args.imageTags, tt.args.matchOption, TagMapping{})
 			assert.Equal(t, tt.want, result)
 		})
 	}
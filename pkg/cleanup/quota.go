@@ -0,0 +1,160 @@
+package cleanup
+
+import (
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Unlimited marks a resource without limit
+const Unlimited = -1
+
+// ImageStreamLimits are the limits of the image streams of a namespace
+type ImageStreamLimits struct {
+	// MaxTags and MaxImages limit each image stream, read from the LimitRanges
+	MaxTags   int
+	MaxImages int
+	// RemainingTags and RemainingImages are left for all image streams of the namespace, read from the ResourceQuotas
+	RemainingTags   int
+	RemainingImages int
+}
+
+// Quota restricts the deletion to as many candidates as needed to get the image stream below its limits
+type Quota struct {
+	ImageStreamLimits
+	// Headroom is the amount of tags and images that should be available below the limits after the cleanup
+	Headroom int
+	// DryRun keeps the remaining quota of the namespace unchanged, since the selected candidates are not deleted
+	DryRun bool
+}
+
+// SelectCandidates returns the candidates that need to be deleted so that the tags and images of the image stream
+// are at least Headroom below the limits. The candidates are selected in the given order, so they are expected in
+// the order the rule would delete them, e.g. oldest commit first. Each candidate is counted together with the
+// companion tags deleted with it (see FilterCompanionTags), which are part of the returned candidates. Since the
+// remaining quota is shared by the image streams of the namespace, it is increased by the deleted tags and images for
+// the following image streams, unless DryRun is set.
+func (q *Quota) SelectCandidates(imageTags []ImageTag, candidates []string) []string {
+	tagCount := len(imageTags)
+	imageRefs := map[string]int{}
+	tagsByName := map[string]ImageTag{}
+	companionsByParent := map[string][]string{}
+	for _, imageTag := range imageTags {
+		tagsByName[imageTag.Name] = imageTag
+		for _, digest := range imageTag.Digests {
+			imageRefs[digest]++
+		}
+		if parent, isCompanion := CompanionParent(imageTag.Name); isCompanion {
+			companionsByParent[parent] = append(companionsByParent[parent], imageTag.Name)
+		}
+	}
+	imageCount := len(imageRefs)
+	maxTags := q.target(q.MaxTags, q.RemainingTags, tagCount)
+	maxImages := q.target(q.MaxImages, q.RemainingImages, imageCount)
+
+	// the companion tags of an image are deleted once no other tag references the image
+	remainingRefs := make(map[string]int, len(imageRefs))
+	for digest, refs := range imageRefs {
+		remainingRefs[digest] = refs
+	}
+	deleted := map[string]bool{}
+	freedImages := 0
+	var deleteTag func(tag string)
+	deleteTag = func(tag string) {
+		if deleted[tag] {
+			return
+		}
+		deleted[tag] = true
+		for _, digest := range tagsByName[tag].Digests {
+			if remainingRefs[digest]--; remainingRefs[digest] == 0 {
+				freedImages++
+				for _, companion := range companionsByParent[digest] {
+					deleteTag(companion)
+				}
+			}
+		}
+	}
+
+	var selected []string
+	for _, candidate := range candidates {
+		if tagCount-len(deleted) <= maxTags && imageCount-freedImages <= maxImages {
+			break
+		}
+		if deleted[candidate] {
+			continue
+		}
+		if parent, isCompanion := CompanionParent(candidate); isCompanion && remainingRefs[parent] > 0 {
+			// companion tags of images that are kept so far are deleted with their parent, if at all
+			continue
+		}
+		selected = append(selected, candidate)
+		deleteTag(candidate)
+	}
+	result := FilterCompanionTags(imageTags, &selected)
+	if !q.DryRun && q.RemainingTags != Unlimited {
+		q.RemainingTags += len(result)
+	}
+	if !q.DryRun && q.RemainingImages != Unlimited {
+		q.RemainingImages += countFreedImages(imageRefs, tagsByName, result)
+	}
+
+	log.WithFields(log.Fields{
+		"tags":       tagCount,
+		"maxTags":    maxTags,
+		"images":     imageCount,
+		"maxImages":  maxImages,
+		"candidates": len(candidates),
+		"selected":   len(result),
+	}).Debug("Selected candidates to get below the image stream limits")
+	return result
+}
+
+// countFreedImages returns the amount of images that are no longer referenced by any tag once the tags are deleted
+func countFreedImages(imageRefs map[string]int, tagsByName map[string]ImageTag, tags []string) int {
+	deletedRefs := map[string]int{}
+	for _, tag := range tags {
+		for _, digest := range tagsByName[tag].Digests {
+			deletedRefs[digest]++
+		}
+	}
+	freedImages := 0
+	for image, refs := range deletedRefs {
+		if refs == imageRefs[image] {
+			freedImages++
+		}
+	}
+	return freedImages
+}
+
+// SortTagsOldestFirst sorts the tags by the time they were last updated, oldest first
+func SortTagsOldestFirst(imageTags []ImageTag, tags []string) {
+	tagsByName := make(map[string]ImageTag, len(imageTags))
+	for _, imageTag := range imageTags {
+		tagsByName[imageTag.Name] = imageTag
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tagsByName[tags[i]].Created.Before(tagsByName[tags[j]].Created)
+	})
+}
+
+// NewUnlimitedImageStreamLimits returns limits without any limit
+func NewUnlimitedImageStreamLimits() ImageStreamLimits {
+	return ImageStreamLimits{MaxTags: Unlimited, MaxImages: Unlimited, RemainingTags: Unlimited, RemainingImages: Unlimited}
+}
+
+// IsLimited returns true if any limit applies
+func (l ImageStreamLimits) IsLimited() bool {
+	return l.MaxTags != Unlimited || l.MaxImages != Unlimited || l.RemainingTags != Unlimited || l.RemainingImages != Unlimited
+}
+
+// target returns the amount of tags or images the image stream may have after the cleanup
+func (q *Quota) target(max, remaining, current int) int {
+	target := current + q.Headroom
+	if max != Unlimited && max < target {
+		target = max
+	}
+	if remaining != Unlimited && current+remaining < target {
+		target = current + remaining
+	}
+	return target - q.Headroom
+}
@@ -0,0 +1,137 @@
+package cleanup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newQuotaTestTags() []ImageTag {
+	images := map[string]string{"t1": "shared", "t2": "shared", "t3": "image-3", "t4": "image-4", "t5": "image-5"}
+	var tags []ImageTag
+	for i, tag := range []string{"t1", "t2", "t3", "t4", "t5"} {
+		tags = append(tags, ImageTag{
+			Name:    tag,
+			Digests: []string{images[tag]},
+			Created: time.Date(2021, 1, i+1, 0, 0, 0, 0, time.UTC),
+		})
+	}
+	return tags
+}
+
+func Test_Quota_SelectCandidates(t *testing.T) {
+	candidates := []string{"t1", "t2", "t4"}
+	withLimits := func(modify func(*ImageStreamLimits)) ImageStreamLimits {
+		limits := NewUnlimitedImageStreamLimits()
+		modify(&limits)
+		return limits
+	}
+	tests := map[string]struct {
+		quota    Quota
+		expected []string
+	}{
+		"GivenNoLimits_ThenSelectNothing": {
+			quota: Quota{ImageStreamLimits: NewUnlimitedImageStreamLimits(), Headroom: 1},
+		},
+		"GivenTagsBelowLimit_ThenSelectNothing": {
+			quota: Quota{ImageStreamLimits: withLimits(func(l *ImageStreamLimits) { l.MaxTags = 10 }), Headroom: 1},
+		},
+		"GivenMaxTags_ThenSelectFirstCandidates": {
+			quota:    Quota{ImageStreamLimits: withLimits(func(l *ImageStreamLimits) { l.MaxTags = 5 }), Headroom: 1},
+			expected: []string{"t1"},
+		},
+		"GivenMaxImages_ThenSelectUntilImagesAreFreed": {
+			quota:    Quota{ImageStreamLimits: withLimits(func(l *ImageStreamLimits) { l.MaxImages = 3 })},
+			expected: []string{"t1", "t2"},
+		},
+		"GivenTooFewCandidates_ThenSelectAllCandidates": {
+			quota:    Quota{ImageStreamLimits: withLimits(func(l *ImageStreamLimits) { l.MaxTags = 1 })},
+			expected: []string{"t1", "t2", "t4"},
+		},
+		"GivenRemainingTags_ThenSelectHeadroom": {
+			quota:    Quota{ImageStreamLimits: withLimits(func(l *ImageStreamLimits) { l.RemainingTags = 0 }), Headroom: 2},
+			expected: []string{"t1", "t2"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.quota.SelectCandidates(newQuotaTestTags(), candidates))
+		})
+	}
+}
+
+func Test_Quota_SelectCandidates_SharesRemainingQuota(t *testing.T) {
+	limits := NewUnlimitedImageStreamLimits()
+	limits.RemainingTags = 0
+	quota := &Quota{ImageStreamLimits: limits, Headroom: 1}
+
+	assert.Equal(t, []string{"t1"}, quota.SelectCandidates(newQuotaTestTags(), []string{"t1", "t2"}))
+	assert.Equal(t, 1, quota.RemainingTags)
+	assert.Empty(t, quota.SelectCandidates(newQuotaTestTags(), []string{"t1", "t2"}))
+}
+
+func Test_Quota_SelectCandidates_DryRun(t *testing.T) {
+	limits := NewUnlimitedImageStreamLimits()
+	limits.RemainingTags = 0
+	quota := &Quota{ImageStreamLimits: limits, Headroom: 1, DryRun: true}
+
+	assert.Equal(t, []string{"t1"}, quota.SelectCandidates(newQuotaTestTags(), []string{"t1", "t2"}))
+	assert.Equal(t, 0, quota.RemainingTags)
+	assert.Equal(t, []string{"t1"}, quota.SelectCandidates(newQuotaTestTags(), []string{"t1", "t2"}))
+}
+
+func Test_Quota_SelectCandidates_KeepsCandidateOrder(t *testing.T) {
+	limits := NewUnlimitedImageStreamLimits()
+	limits.MaxTags = 4
+	quota := &Quota{ImageStreamLimits: limits}
+
+	assert.Equal(t, []string{"t4"}, quota.SelectCandidates(newQuotaTestTags(), []string{"t4", "t1"}))
+}
+
+func Test_Quota_SelectCandidates_Companions(t *testing.T) {
+	imageTags := []ImageTag{
+		{Name: "a", Digests: []string{digestOf("a")}},
+		{Name: "b", Digests: []string{digestOf("b")}},
+		{Name: "c", Digests: []string{digestOf("c")}},
+		{Name: companionOf("a", ".sig"), Digests: []string{digestOf("1")}},
+		{Name: companionOf("b", ".sig"), Digests: []string{digestOf("2")}},
+		{Name: companionOf("c", ".sig"), Digests: []string{digestOf("3")}},
+	}
+	tests := map[string]struct {
+		maxTags           int
+		candidates        []string
+		expected          []string
+		expectedRemaining int
+	}{
+		"GivenCandidateWithSignature_ThenCountSignature": {
+			maxTags:           4,
+			candidates:        []string{"a", "b"},
+			expected:          []string{"a", companionOf("a", ".sig")},
+			expectedRemaining: 2,
+		},
+		"GivenSignatureBeforeItsImage_ThenDeleteSignatureWithImage": {
+			maxTags:           4,
+			candidates:        []string{companionOf("a", ".sig"), "a", "b"},
+			expected:          []string{"a", companionOf("a", ".sig")},
+			expectedRemaining: 2,
+		},
+		"GivenSignatureOfKeptImage_ThenSkipSignature": {
+			maxTags:           5,
+			candidates:        []string{companionOf("c", ".sig"), "b"},
+			expected:          []string{"b", companionOf("b", ".sig")},
+			expectedRemaining: 2,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			limits := NewUnlimitedImageStreamLimits()
+			limits.MaxTags = tt.maxTags
+			limits.RemainingTags = 0
+			quota := &Quota{ImageStreamLimits: limits}
+
+			assert.Equal(t, tt.expected, quota.SelectCandidates(imageTags, tt.candidates))
+			assert.Equal(t, tt.expectedRemaining, quota.RemainingTags)
+		})
+	}
+}
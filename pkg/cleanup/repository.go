@@ -0,0 +1,266 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/appuio/seiso/pkg/git"
+	log "github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
+)
+
+type (
+	// ImageTag is a tag of an image, independent of the repository providing it
+	ImageTag struct {
+		// Name is the name of the tag
+		Name string
+		// Digests are the digests of the images of the tag, the current image first. Repositories keeping the history
+		// of their tags (e.g. OpenShift ImageStreams) return the previous images after it.
+		Digests []string
+		// Created is the time the tag was last updated
+		Created time.Time
+	}
+	// ImageRepository provides the tags of images, e.g. from OpenShift ImageStreams or a container registry
+	ImageRepository interface {
+		// GetTags returns the tags of the given image together with their history
+		GetTags(ctx context.Context, image string) ([]ImageTag, error)
+		// GetActiveTags returns the subset of the given tags that are referenced by resources in the cluster
+		GetActiveTags(ctx context.Context, image string, tags []string) ([]string, error)
+		// DeleteTags deletes the given tags of the image. The tags are deleted together, since in some repositories
+		// (e.g. registries deleting by digest) removing one tag can affect others.
+		DeleteTags(ctx context.Context, image string, tags []string) error
+	}
+	// ImageLister is implemented by image repositories that can enumerate their images
+	ImageLister interface {
+		// ListImages returns the names of the images matching the label selector. An empty selector matches all images.
+		ListImages(ctx context.Context, selector string) ([]string, error)
+	}
+	// HistoryItemRepository is implemented by image repositories that keep the history of the images of each tag
+	HistoryItemRepository interface {
+		ImageRepository
+		// GetActiveImages returns the subset of the given image digests that are referenced or running in the cluster
+		GetActiveImages(ctx context.Context, image string, digests []string) ([]string, error)
+		// DeleteHistoryItems removes the items with the given image digests from the history of the tags, by tag
+		DeleteHistoryItems(ctx context.Context, image string, items map[string][]string) error
+	}
+	// BlobResolver is implemented by image repositories that can resolve the blobs of their images
+	BlobResolver interface {
+		// GetImageBlobs returns the size in bytes of each blob of the image with the given digest, by blob digest
+		GetImageBlobs(ctx context.Context, digest string) (map[string]int64, error)
+		// GetCachedTags returns the tags of the image read by GetTags, or reads them if they are not cached
+		GetCachedTags(ctx context.Context, image string) ([]ImageTag, error)
+	}
+	// HistoryOptions configures the selection of history candidates
+	HistoryOptions struct {
+		MatchOption MatchOption
+		// TagMapping extracts the git commit or tag from the image tags
+		TagMapping TagMapping
+		// Keep is the amount of most current matching tags that are kept
+		Keep int
+		// VersionRetention additionally keeps tags by their semantic version, e.g. the newest of each major version
+		VersionRetention git.VersionRetention
+		// Releases maps git tag names to their commit. The tags of releases are never candidates.
+		Releases map[string]string
+		// Quota restricts the candidates to the oldest ones needed to get below the image stream limits
+		Quota *Quota
+	}
+	// OrphanOptions configures the selection of orphan candidates
+	OrphanOptions struct {
+		MatchOption MatchOption
+		// TagMapping extracts the git commit or tag from the image tags
+		TagMapping TagMapping
+		// OlderThan excludes tags that were updated after this time
+		OlderThan time.Time
+		// DeletionPattern restricts the candidates to tags matching the pattern
+		DeletionPattern *regexp.Regexp
+		// BranchPattern extracts the branch from the tags. If set, the git candidates are branch names and the tags
+		// of branches that do not exist anymore are returned, instead of the tags without matching commit.
+		BranchPattern *regexp.Regexp
+		// Releases maps git tag names to their commit. The tags of releases are never candidates.
+		Releases map[string]string
+		// Quota restricts the candidates to the oldest ones needed to get below the image stream limits
+		Quota *Quota
+	}
+	// HistoryItemOptions configures the selection of history item candidates
+	HistoryItemOptions struct {
+		// Keep is the amount of newest items that are kept per tag, including the current image of the tag
+		Keep int
+	}
+	// RetainOptions configures the selection of retain candidates, which only depends on the tag metadata
+	RetainOptions struct {
+		// Keep is the amount of most recently created tags that are kept per group
+		Keep int
+		// Include restricts the candidates to tags matching the pattern
+		Include *regexp.Regexp
+		// Exclude removes tags matching the pattern from the candidates
+		Exclude *regexp.Regexp
+		// GroupPattern groups the tags by the first capture group (or the whole match) of the pattern. Tags not
+		// matching the pattern form a group of their own.
+		GroupPattern *regexp.Regexp
+	}
+)
+
+// GetHistoryCandidates returns the inactive image tags matching the git candidates, except the most current ones to keep
+func GetHistoryCandidates(ctx context.Context, repository ImageRepository, image string, gitCandidates []string, options HistoryOptions) ([]string, error) {
+	imageTags, err := repository.GetTags(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve image tags of '%s': %w", image, err)
+	}
+
+	var tagNames []string
+	for _, imageTag := range imageTags {
+		tagNames = append(tagNames, imageTag.Name)
+	}
+
+	matchingTags := GetMatchingTags(&gitCandidates, &tagNames, options.MatchOption, options.TagMapping)
+
+	activeTags, err := repository.GetActiveTags(ctx, image, matchingTags)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve active image tags of '%s': %w", image, err)
+	}
+
+	inactiveTags := GetInactiveImageTags(&activeTags, &matchingTags)
+	candidates := LimitTags(&inactiveTags, options.Keep)
+	if options.VersionRetention.IsEnabled() {
+		protectedTags := options.VersionRetention.ProtectedTags(inactiveTags)
+		candidates = GetInactiveImageTags(&protectedTags, &candidates)
+	}
+	if len(options.Releases) > 0 {
+		candidates = FilterReleaseTags(options.Releases, &candidates, options.TagMapping)
+	}
+	if options.Quota != nil {
+		// the candidates are in git order, newest commit first
+		return options.Quota.SelectCandidates(imageTags, funk.ReverseStrings(candidates)), nil
+	}
+	return FilterCompanionTags(imageTags, &candidates), nil
+}
+
+// GetOrphanCandidates returns the inactive image tags older than the cut-off date that do not match any git candidate,
+// or with a branch pattern, whose branch is not among the git candidates
+func GetOrphanCandidates(ctx context.Context, repository ImageRepository, image string, gitCandidates []string, options OrphanOptions) ([]string, error) {
+	imageTags, err := repository.GetTags(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve image tags of '%s': %w", image, err)
+	}
+
+	imageTagList := FilterImageTagsByTime(&imageTags, options.OlderThan)
+	if options.BranchPattern != nil {
+		imageTagList = FilterVanishedBranchTags(&gitCandidates, &imageTagList, options.BranchPattern)
+	} else {
+		imageTagList = FilterOrphanImageTags(&gitCandidates, &imageTagList, options.MatchOption, options.TagMapping)
+	}
+	if options.DeletionPattern != nil {
+		imageTagList = FilterByRegex(&imageTagList, options.DeletionPattern)
+	}
+	if len(options.Releases) > 0 {
+		imageTagList = FilterReleaseTags(options.Releases, &imageTagList, options.TagMapping)
+	}
+	candidates, err := FilterActiveImageTags(ctx, repository, image, imageTagList, &imageTagList)
+	if err != nil {
+		return nil, err
+	}
+	if options.Quota != nil {
+		// orphans have no order of their own, they are only selected by age
+		SortTagsOldestFirst(imageTags, candidates)
+		return options.Quota.SelectCandidates(imageTags, candidates), nil
+	}
+	return FilterCompanionTags(imageTags, &candidates), nil
+}
+
+// GetRetainCandidates returns the inactive image tags matching the include and exclude patterns, except the most
+// recently created ones to keep in each group
+func GetRetainCandidates(ctx context.Context, repository ImageRepository, image string, options RetainOptions) ([]string, error) {
+	imageTags, err := repository.GetTags(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve image tags of '%s': %w", image, err)
+	}
+
+	SortImageTagsByCreation(imageTags)
+	var tagNames []string
+	for _, imageTag := range imageTags {
+		tagNames = append(tagNames, imageTag.Name)
+	}
+	if options.Include != nil {
+		tagNames = FilterByRegex(&tagNames, options.Include)
+	}
+	if options.Exclude != nil {
+		tagNames = funk.FilterString(tagNames, func(tag string) bool {
+			return !options.Exclude.MatchString(tag)
+		})
+	}
+
+	inactiveTags, err := FilterActiveImageTags(ctx, repository, image, tagNames, &tagNames)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []string
+	groups, groupTags := GroupTagsByPattern(inactiveTags, options.GroupPattern)
+	for _, group := range groups {
+		tags := groupTags[group]
+		log.WithFields(log.Fields{
+			"group": group,
+			"tags":  tags,
+		}).Debug("Limiting tags of group")
+		candidates = append(candidates, LimitTags(&tags, options.Keep)...)
+	}
+	return FilterCompanionTags(imageTags, &candidates), nil
+}
+
+// GetHistoryItemCandidates returns the image digests in the history of each tag, except the newest items to keep and
+// the images that are in use. Images that are also among the kept items of the tag are not candidates.
+func GetHistoryItemCandidates(ctx context.Context, repository HistoryItemRepository, image string, options HistoryItemOptions) (map[string][]string, error) {
+	imageTags, err := repository.GetTags(ctx, image)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve image tags of '%s': %w", image, err)
+	}
+
+	candidates := map[string][]string{}
+	var digests []string
+	for _, imageTag := range imageTags {
+		var keptImages []string
+		for i, digest := range imageTag.Digests {
+			if i < options.Keep {
+				keptImages = append(keptImages, digest)
+				continue
+			}
+			if funk.ContainsString(keptImages, digest) || funk.ContainsString(candidates[imageTag.Name], digest) {
+				continue
+			}
+			candidates[imageTag.Name] = append(candidates[imageTag.Name], digest)
+			if !funk.ContainsString(digests, digest) {
+				digests = append(digests, digest)
+			}
+		}
+	}
+	if len(digests) == 0 {
+		return candidates, nil
+	}
+
+	activeImages, err := repository.GetActiveImages(ctx, image, digests)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve active images of '%s': %w", image, err)
+	}
+	for tag, tagDigests := range candidates {
+		inactiveDigests := funk.SubtractString(tagDigests, activeImages)
+		if len(inactiveDigests) == 0 {
+			delete(candidates, tag)
+			continue
+		}
+		candidates[tag] = inactiveDigests
+	}
+	return candidates, nil
+}
+
+// TagDigests maps the tags to the image digests of their history
+func TagDigests(imageTags []ImageTag) map[string][]string {
+	digests := make(map[string][]string, len(imageTags))
+	for _, imageTag := range imageTags {
+		if len(imageTag.Digests) > 0 {
+			digests[imageTag.Name] = imageTag.Digests
+		}
+	}
+	return digests
+}
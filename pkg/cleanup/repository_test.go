@@ -0,0 +1,448 @@
+package cleanup_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appuio/seiso/pkg/cleanup"
+	"github.com/appuio/seiso/pkg/git"
+	"github.com/appuio/seiso/pkg/kubernetes"
+	"github.com/appuio/seiso/pkg/openshift"
+	imagev1 "github.com/openshift/api/image/v1"
+	imagefake "github.com/openshift/client-go/image/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const testNamespace = "namespace"
+
+func newTestImageStream(name string, tags map[string]time.Time) *imagev1.ImageStream {
+	imageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace}}
+	for tag, created := range tags {
+		imageStream.Status.Tags = append(imageStream.Status.Tags, imagev1.NamedTagEventList{
+			Tag:   tag,
+			Items: []imagev1.TagEvent{{Created: metav1.NewTime(created)}},
+		})
+	}
+	return imageStream
+}
+
+func newTestPod(name, image string) *corev1.Pod {
+	return newTestPodInNamespace(testNamespace, name, image)
+}
+
+func newTestPodInNamespace(namespace, name, image string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+	}
+}
+
+// newTestRepository creates an ImageStreamRepository backed by fake clients
+func newTestRepository(imageStreams []runtime.Object, resources ...runtime.Object) (*openshift.ImageStreamRepository, *imagefake.Clientset) {
+	return newTestRepositoryWithOptions(openshift.ActiveImageOptions{}, imageStreams, resources...)
+}
+
+func newTestRepositoryWithOptions(activeOptions openshift.ActiveImageOptions, imageStreams []runtime.Object, resources ...runtime.Object) (*openshift.ImageStreamRepository, *imagefake.Clientset) {
+	customListKinds := map[string]string{
+		"deploymentconfigs": "DeploymentConfigList",
+		"buildconfigs":      "BuildConfigList",
+		"imagestreams":      "ImageStreamList",
+		"cronjobs":          "CronJobList",
+	}
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, resource := range openshift.ImageReferenceResources {
+		if listKind, ok := customListKinds[resource.Resource]; ok {
+			listKinds[resource] = listKind
+		}
+	}
+	dynamicClient := dynfake.NewSimpleDynamicClientWithCustomListKinds(scheme.Scheme, listKinds, resources...)
+	imageClient := imagefake.NewSimpleClientset(imageStreams...)
+	return openshift.NewImageStreamRepository(imageClient.ImageV1(), kubernetes.NewFromClient(dynamicClient), testNamespace, activeOptions), imageClient
+}
+
+func Test_GetHistoryCandidates(t *testing.T) {
+	now := time.Now()
+	repository, _ := newTestRepository(
+		[]runtime.Object{newTestImageStream("app", map[string]time.Time{
+			"a1": now, "a2": now, "a5": now, "unknown": now,
+		})},
+		newTestPod("app-1", "docker-registry.default.svc:5000/namespace/app:a5"),
+	)
+
+	candidates, err := cleanup.GetHistoryCandidates(context.Background(), repository, "app", []string{"a5", "a2", "a1"}, cleanup.HistoryOptions{
+		MatchOption: cleanup.MatchOptionExact,
+		Keep:        1,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, candidates)
+}
+
+func Test_GetHistoryCandidates_ActiveInOtherNamespace(t *testing.T) {
+	now := time.Now()
+	imageStreams := []runtime.Object{newTestImageStream("app", map[string]time.Time{"a1": now, "a2": now, "a3": now})}
+	resources := []runtime.Object{
+		newTestPodInNamespace("prod", "app-1", "docker-registry.default.svc:5000/namespace/app:a1"),
+		newTestPodInNamespace("test", "app-1", "docker-registry.default.svc:5000/namespace/app:a2"),
+		newTestPodInNamespace("other", "app-1", "docker-registry.default.svc:5000/other/app:a3"),
+	}
+	tests := map[string]struct {
+		namespaces []string
+		expected   []string
+	}{
+		"ShouldIgnoreOtherNamespaces_ByDefault": {
+			expected: []string{"a1", "a2", "a3"},
+		},
+		"ShouldKeepTags_UsedInOtherNamespaces": {
+			namespaces: []string{"prod", "test", "other"},
+			expected:   []string{"a3"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			repository, _ := newTestRepositoryWithOptions(openshift.ActiveImageOptions{Namespaces: tt.namespaces}, imageStreams, resources...)
+
+			candidates, err := cleanup.GetHistoryCandidates(context.Background(), repository, "app", []string{"a1", "a2", "a3"}, cleanup.HistoryOptions{
+				MatchOption: cleanup.MatchOptionExact,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, candidates)
+		})
+	}
+}
+
+func Test_GetHistoryCandidates_RunningDigest(t *testing.T) {
+	imageStream := newTestImageStream("app", map[string]time.Time{"a1": time.Now(), "a2": time.Now()})
+	for i := range imageStream.Status.Tags {
+		imageStream.Status.Tags[i].Items[0].Image = "sha256:" + imageStream.Status.Tags[i].Tag
+	}
+	pod := newTestPod("app-1", "docker-registry.default.svc:5000/namespace/app:moved")
+	pod.Status = corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		ContainerStatuses: []corev1.ContainerStatus{{
+			Name:    "app",
+			ImageID: "docker-pullable://docker-registry.default.svc:5000/namespace/app@sha256:a1",
+		}},
+	}
+	for _, mode := range []string{openshift.ReferenceModeStructured, openshift.ReferenceModeSubstring} {
+		t.Run(mode, func(t *testing.T) {
+			repository, _ := newTestRepositoryWithOptions(openshift.ActiveImageOptions{ReferenceMode: mode}, []runtime.Object{imageStream}, pod)
+
+			candidates, err := cleanup.GetHistoryCandidates(context.Background(), repository, "app", []string{"a2", "a1"}, cleanup.HistoryOptions{
+				MatchOption: cleanup.MatchOptionExact,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"a2"}, candidates)
+		})
+	}
+}
+
+func Test_GetOrphanCandidates(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-24 * time.Hour)
+	repository, _ := newTestRepository(
+		[]runtime.Object{newTestImageStream("app", map[string]time.Time{
+			"a1": old, "b3": old, "b4": old, "c6": now, "latest": old, "active": old,
+		})},
+		newTestPod("app-1", "namespace/app:active"),
+	)
+
+	candidates, err := cleanup.GetOrphanCandidates(context.Background(), repository, "app", []string{"a1"}, cleanup.OrphanOptions{
+		MatchOption:     cleanup.MatchOptionExact,
+		OlderThan:       now.Add(-time.Hour),
+		DeletionPattern: regexp.MustCompile("^[a-z][0-9]$|^active$"),
+	})
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"b3", "b4"}, candidates)
+}
+
+func Test_GetOrphanCandidates_BranchPattern(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-24 * time.Hour)
+	repository, _ := newTestRepository(
+		[]runtime.Object{newTestImageStream("app", map[string]time.Time{
+			"master-1a2b3c4": old, "feature-a-5d6e7f8": old, "feature-b-9a8b7c6": old, "feature-c-1234567": now,
+			"feature-d-7654321": old, "latest": old,
+		})},
+		newTestPod("app-1", "namespace/app:feature-d-7654321"),
+	)
+
+	candidates, err := cleanup.GetOrphanCandidates(context.Background(), repository, "app", []string{"master", "feature/a"}, cleanup.OrphanOptions{
+		OlderThan:     now.Add(-time.Hour),
+		BranchPattern: regexp.MustCompile("^(.+)-[0-9a-f]{7}$"),
+	})
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"feature-b-9a8b7c6"}, candidates)
+}
+
+func Test_GetOrphanCandidates_Releases(t *testing.T) {
+	old := time.Now().Add(-24 * time.Hour)
+	repository, _ := newTestRepository([]runtime.Object{newTestImageStream("app", map[string]time.Time{
+		"a1": old, "b3": old, "r9": old, "v1.0.0": old,
+	})})
+
+	candidates, err := cleanup.GetOrphanCandidates(context.Background(), repository, "app", []string{"a1"}, cleanup.OrphanOptions{
+		MatchOption: cleanup.MatchOptionPrefix,
+		OlderThan:   time.Now(),
+		Releases:    map[string]string{"v1.0.0": "r9"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b3"}, candidates)
+}
+
+func Test_GetOrphanCandidates_Quota(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	repository, _ := newTestRepository([]runtime.Object{newTestImageStream("app", map[string]time.Time{
+		"a1": day(5), "b2": day(2), "b3": day(1), "b4": day(3),
+	})})
+	limits := cleanup.NewUnlimitedImageStreamLimits()
+	limits.MaxTags = 4
+
+	candidates, err := cleanup.GetOrphanCandidates(context.Background(), repository, "app", []string{"a1"}, cleanup.OrphanOptions{
+		MatchOption: cleanup.MatchOptionPrefix,
+		OlderThan:   time.Now(),
+		Quota:       &cleanup.Quota{ImageStreamLimits: limits, Headroom: 2},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b3", "b2"}, candidates)
+}
+
+func Test_GetHistoryCandidates_Quota(t *testing.T) {
+	now := time.Now()
+	// the tag of the oldest commit was pushed last, e.g. by a rebuild
+	repository, _ := newTestRepository([]runtime.Object{newTestImageStream("app", map[string]time.Time{
+		"a1": now, "a2": now.Add(-time.Hour), "a3": now.Add(-2 * time.Hour),
+	})})
+	limits := cleanup.NewUnlimitedImageStreamLimits()
+	limits.MaxTags = 3
+
+	candidates, err := cleanup.GetHistoryCandidates(context.Background(), repository, "app", []string{"a3", "a2", "a1"}, cleanup.HistoryOptions{
+		MatchOption: cleanup.MatchOptionPrefix,
+		Quota:       &cleanup.Quota{ImageStreamLimits: limits, Headroom: 1},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, candidates)
+}
+
+func Test_GetHistoryCandidates_Releases(t *testing.T) {
+	now := time.Now()
+	repository, _ := newTestRepository([]runtime.Object{newTestImageStream("app", map[string]time.Time{
+		"a1": now, "a2": now, "a3": now,
+	})})
+
+	candidates, err := cleanup.GetHistoryCandidates(context.Background(), repository, "app", []string{"a3", "a2", "a1"}, cleanup.HistoryOptions{
+		MatchOption: cleanup.MatchOptionPrefix,
+		Keep:        1,
+		Releases:    map[string]string{"v0.9.0": "a1"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a2"}, candidates)
+}
+
+func Test_GetHistoryCandidates_VersionRetention(t *testing.T) {
+	now := time.Now()
+	gitTags := []string{"v2.0.2", "v2.0.1", "v2.0.0", "v1.1.0", "v1.0.0"}
+	tags := map[string]time.Time{}
+	for _, tag := range gitTags {
+		tags[tag] = now
+	}
+	repository, _ := newTestRepository([]runtime.Object{newTestImageStream("app", tags)})
+
+	candidates, err := cleanup.GetHistoryCandidates(context.Background(), repository, "app", gitTags, cleanup.HistoryOptions{
+		MatchOption:      cleanup.MatchOptionExact,
+		Keep:             2,
+		VersionRetention: git.VersionRetention{NewestPerMajor: true},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v2.0.0", "v1.0.0"}, candidates)
+}
+
+func Test_GetRetainCandidates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	imageStreams := []runtime.Object{newTestImageStream("app", map[string]time.Time{
+		"main-101":    day(1),
+		"main-102":    day(2),
+		"main-103":    day(3),
+		"feature-201": day(4),
+		"feature-202": day(5),
+		"latest":      day(6),
+	})}
+	pod := newTestPod("app-1", "docker-registry.default.svc:5000/namespace/app:main-101")
+	tests := map[string]struct {
+		options  cleanup.RetainOptions
+		expected []string
+	}{
+		"ShouldKeepNewestTags_ByCreation": {
+			options:  cleanup.RetainOptions{Keep: 2},
+			expected: []string{"feature-201", "main-103", "main-102"},
+		},
+		"ShouldOnlyConsiderIncludedTags": {
+			options:  cleanup.RetainOptions{Keep: 1, Include: regexp.MustCompile("^main-")},
+			expected: []string{"main-102"},
+		},
+		"ShouldIgnoreExcludedTags": {
+			options:  cleanup.RetainOptions{Keep: 1, Exclude: regexp.MustCompile("^latest$")},
+			expected: []string{"feature-201", "main-103", "main-102"},
+		},
+		"ShouldKeepNewestTags_PerGroup": {
+			options:  cleanup.RetainOptions{Keep: 1, GroupPattern: regexp.MustCompile("^(.*)-[0-9]+$")},
+			expected: []string{"feature-201", "main-102"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			repository, _ := newTestRepository(imageStreams, pod)
+
+			candidates, err := cleanup.GetRetainCandidates(context.Background(), repository, "app", tt.options)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, candidates)
+		})
+	}
+}
+
+func Test_GetHistoryCandidates_MissingImageStream(t *testing.T) {
+	repository, _ := newTestRepository(nil)
+
+	_, err := cleanup.GetHistoryCandidates(context.Background(), repository, "app", []string{"a1"}, cleanup.HistoryOptions{})
+
+	assert.Error(t, err)
+}
+
+func Test_ImageStreamRepository_DeleteTags(t *testing.T) {
+	repository, imageClient := newTestRepository(nil)
+	ctx := context.Background()
+	_, err := imageClient.ImageV1().ImageStreamTags(testNamespace).Create(ctx, &imagev1.ImageStreamTag{
+		ObjectMeta: metav1.ObjectMeta{Name: "app:a1", Namespace: testNamespace},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	assert.NoError(t, repository.DeleteTags(ctx, "app", []string{"a1"}))
+	assert.Error(t, repository.DeleteTags(ctx, "app", []string{"a2"}))
+}
+
+func Test_GetHistoryItemCandidates(t *testing.T) {
+	imageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: testNamespace}}
+	imageStream.Status.Tags = []imagev1.NamedTagEventList{
+		{Tag: "latest", Items: []imagev1.TagEvent{
+			{Image: "sha256:l5"}, {Image: "sha256:l4"}, {Image: "sha256:l3"}, {Image: "sha256:l5"},
+			{Image: "sha256:referenced"}, {Image: "sha256:running"}, {Image: "sha256:l1"},
+		}},
+		{Tag: "stable", Items: []imagev1.TagEvent{{Image: "sha256:s2"}, {Image: "sha256:s1"}}},
+		{Tag: "v1", Items: []imagev1.TagEvent{{Image: "sha256:v1"}}},
+	}
+	referencingPod := newTestPod("app-1", "docker-registry.default.svc:5000/namespace/app@sha256:referenced")
+	runningPod := newTestPod("app-2", "docker-registry.default.svc:5000/namespace/app:latest")
+	runningPod.Status = corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		ContainerStatuses: []corev1.ContainerStatus{{
+			Name:    "app",
+			ImageID: "docker-pullable://docker-registry.default.svc:5000/namespace/app@sha256:running",
+		}},
+	}
+	repository, _ := newTestRepository([]runtime.Object{imageStream}, referencingPod, runningPod)
+
+	candidates, err := cleanup.GetHistoryItemCandidates(context.Background(), repository, "app", cleanup.HistoryItemOptions{Keep: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"latest": {"sha256:l3", "sha256:l1"}}, candidates)
+}
+
+func Test_ImageStreamRepository_DeleteHistoryItems(t *testing.T) {
+	imageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: testNamespace}}
+	imageStream.Status.Tags = []imagev1.NamedTagEventList{
+		{Tag: "latest", Items: []imagev1.TagEvent{{Image: "sha256:l3"}, {Image: "sha256:l2"}, {Image: "sha256:l1"}}},
+		{Tag: "stable", Items: []imagev1.TagEvent{{Image: "sha256:l1"}}},
+	}
+	repository, imageClient := newTestRepository([]runtime.Object{imageStream})
+	ctx := context.Background()
+
+	// the newest item is never removed, even if requested
+	err := repository.DeleteHistoryItems(ctx, "app", map[string][]string{"latest": {"sha256:l3", "sha256:l1"}, "stable": {"sha256:l1"}})
+
+	require.NoError(t, err)
+	updated, err := imageClient.ImageV1().ImageStreams(testNamespace).Get(ctx, "app", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []imagev1.NamedTagEventList{
+		{Tag: "latest", Items: []imagev1.TagEvent{{Image: "sha256:l3"}, {Image: "sha256:l2"}}},
+		{Tag: "stable", Items: []imagev1.TagEvent{{Image: "sha256:l1"}}},
+	}, updated.Status.Tags)
+}
+
+func Test_GetHistoryCandidates_Promoted(t *testing.T) {
+	imageStream := newTestImageStream("app", map[string]time.Time{"a1": time.Now(), "a2": time.Now(), "a3": time.Now()})
+	for i := range imageStream.Status.Tags {
+		imageStream.Status.Tags[i].Items[0].Image = "sha256:" + imageStream.Status.Tags[i].Tag
+	}
+	prodImageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "prod"}}
+	prodImageStream.Spec.Tags = []imagev1.TagReference{{
+		Name: "current",
+		From: &corev1.ObjectReference{Kind: "ImageStreamTag", Namespace: testNamespace, Name: "app:a1"},
+	}}
+	prodImageStream.Status.Tags = []imagev1.NamedTagEventList{{Tag: "previous", Items: []imagev1.TagEvent{{Image: "sha256:a2"}}}}
+	repository, _ := newTestRepositoryWithOptions(openshift.ActiveImageOptions{PromotionNamespaces: []string{"prod"}},
+		[]runtime.Object{imageStream, prodImageStream})
+
+	candidates, err := cleanup.GetHistoryCandidates(context.Background(), repository, "app", []string{"a3", "a2", "a1"}, cleanup.HistoryOptions{
+		MatchOption: cleanup.MatchOptionExact,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a3"}, candidates)
+}
+
+func Test_GetOrphanCandidates_Companions(t *testing.T) {
+	commitA, commitB := strings.Repeat("a", 40), strings.Repeat("b", 40)
+	digestA, digestB := strings.Repeat("a", 64), strings.Repeat("b", 64)
+	images := map[string]string{
+		commitA:                      "sha256:" + digestA,
+		commitB:                      "sha256:" + digestB,
+		"sha256-" + digestA + ".sig": "sha256:" + strings.Repeat("c", 64),
+		"sha256-" + digestB + ".sig": "sha256:" + strings.Repeat("d", 64),
+	}
+	imageStream := newTestImageStream("app", map[string]time.Time{})
+	for tag, image := range images {
+		imageStream.Status.Tags = append(imageStream.Status.Tags, imagev1.NamedTagEventList{
+			Tag:   tag,
+			Items: []imagev1.TagEvent{{Image: image, Created: metav1.NewTime(time.Now().Add(-time.Hour))}},
+		})
+	}
+	repository, _ := newTestRepository([]runtime.Object{imageStream})
+
+	candidates, err := cleanup.GetOrphanCandidates(context.Background(), repository, "app", []string{commitA}, cleanup.OrphanOptions{
+		MatchOption:     cleanup.MatchOptionPrefix,
+		OlderThan:       time.Now(),
+		DeletionPattern: regexp.MustCompile("^[a-z0-9]{40}$"),
+	})
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{commitB, "sha256-" + digestB + ".sig"}, candidates)
+}
+
+func Test_TagDigests(t *testing.T) {
+	digests := cleanup.TagDigests([]cleanup.ImageTag{
+		{Name: "v1", Digests: []string{"sha256:new", "sha256:old"}},
+		{Name: "v2"},
+	})
+
+	assert.Equal(t, map[string][]string{"v1": {"sha256:new", "sha256:old"}}, digests)
+}
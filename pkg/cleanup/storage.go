@@ -0,0 +1,145 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// StorageReport is the registry storage that is reclaimable by deleting the candidate tags of an image
+type StorageReport struct {
+	// Tags maps the candidate tags to their reclaimable bytes. Blobs shared by several candidates are counted for the
+	// first candidate only, so that the sizes add up to the total.
+	Tags map[string]int64
+	// Total is the amount of reclaimable bytes of all candidates
+	Total int64
+}
+
+// BlobIndex maps the blobs of the images in the history of all tags of all images to the images referencing them. It
+// is built once per run, so that the storage report of each image does not resolve the other images again.
+type BlobIndex struct {
+	resolver BlobResolver
+	// blobs caches the blobs of the resolved image digests
+	blobs map[string]map[string]int64
+	// users maps the blobs to the names of the images referencing them
+	users map[string]map[string]bool
+}
+
+// NewBlobIndex resolves the blobs of the images in the history of all tags of the images of the repository. Only the
+// given image is indexed if the repository cannot list its images.
+func NewBlobIndex(ctx context.Context, repository ImageRepository) (*BlobIndex, error) {
+	resolver, ok := repository.(BlobResolver)
+	if !ok {
+		return nil, errors.New("the image repository does not support resolving image blobs")
+	}
+	index := &BlobIndex{
+		resolver: resolver,
+		blobs:    map[string]map[string]int64{},
+		users:    map[string]map[string]bool{},
+	}
+	lister, ok := repository.(ImageLister)
+	if !ok {
+		return index, nil
+	}
+	images, err := lister.ListImages(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("could not list images: %w", err)
+	}
+	for _, image := range images {
+		imageTags, err := repository.GetTags(ctx, image)
+		if err != nil {
+			return nil, fmt.Errorf("could not retrieve image tags of '%s': %w", image, err)
+		}
+		for _, digests := range TagDigests(imageTags) {
+			for _, digest := range digests {
+				blobs, err := index.getBlobs(ctx, digest)
+				if err != nil {
+					return nil, err
+				}
+				for blob := range blobs {
+					if index.users[blob] == nil {
+						index.users[blob] = map[string]bool{}
+					}
+					index.users[blob][image] = true
+				}
+			}
+		}
+	}
+	return index, nil
+}
+
+// getBlobs returns the blobs of the image digest, resolving each digest once
+func (i *BlobIndex) getBlobs(ctx context.Context, digest string) (map[string]int64, error) {
+	if blobs, found := i.blobs[digest]; found {
+		return blobs, nil
+	}
+	blobs, err := i.resolver.GetImageBlobs(ctx, digest)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve image '%s': %w", digest, err)
+	}
+	i.blobs[digest] = blobs
+	return blobs, nil
+}
+
+// isUsedByOtherImage returns true if the blob is referenced by a tag of another image than the given one
+func (i *BlobIndex) isUsedByOtherImage(blob, image string) bool {
+	for user := range i.users[blob] {
+		if user != image {
+			return true
+		}
+	}
+	return false
+}
+
+// GetReclaimableStorage sums up the sizes of the blobs of the images in the history of the candidate tags that are not
+// referenced by any kept tag of the image, nor by any tag of the other images of the index. Each blob is counted once.
+// The storage is only reclaimed once the registry garbage collects the unreferenced blobs, e.g. by pruning the images.
+// The tags of the image are the ones the candidates were selected from, cached by the repository.
+func GetReclaimableStorage(ctx context.Context, index *BlobIndex, image string, candidates []string) (StorageReport, error) {
+	report := StorageReport{Tags: make(map[string]int64, len(candidates))}
+	imageTags, err := index.resolver.GetCachedTags(ctx, image)
+	if err != nil {
+		return report, fmt.Errorf("could not retrieve image tags of '%s': %w", image, err)
+	}
+
+	isCandidate := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		isCandidate[candidate] = true
+	}
+	digests := TagDigests(imageTags)
+	keptBlobs := map[string]bool{}
+	for tag, tagDigests := range digests {
+		if isCandidate[tag] {
+			continue
+		}
+		for _, digest := range tagDigests {
+			blobs, err := index.getBlobs(ctx, digest)
+			if err != nil {
+				return report, err
+			}
+			for blob := range blobs {
+				keptBlobs[blob] = true
+			}
+		}
+	}
+
+	counted := map[string]bool{}
+	for _, candidate := range candidates {
+		report.Tags[candidate] = 0
+		for _, digest := range digests[candidate] {
+			blobs, err := index.getBlobs(ctx, digest)
+			if err != nil {
+				return report, err
+			}
+			for blob, size := range blobs {
+				if keptBlobs[blob] || counted[blob] || index.isUsedByOtherImage(blob, image) {
+					continue
+				}
+				counted[blob] = true
+				report.Tags[candidate] += size
+				report.Total += size
+			}
+		}
+	}
+	return report, nil
+}
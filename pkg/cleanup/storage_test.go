@@ -0,0 +1,81 @@
+package cleanup_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/appuio/seiso/pkg/cleanup"
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newTestImage(digest string, layers map[string]int64) *imagev1.Image {
+	image := &imagev1.Image{ObjectMeta: metav1.ObjectMeta{Name: digest}}
+	for name, size := range layers {
+		image.DockerImageLayers = append(image.DockerImageLayers, imagev1.ImageLayer{Name: name, LayerSize: size})
+	}
+	return image
+}
+
+func Test_GetReclaimableStorage(t *testing.T) {
+	imageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: testNamespace}}
+	for tag, images := range map[string][]string{
+		"kept":  {"sha256:kept"},
+		"old-1": {"sha256:old-1", "sha256:older-1"},
+		"old-2": {"sha256:old-2"},
+	} {
+		tagEvents := imagev1.NamedTagEventList{Tag: tag}
+		for _, image := range images {
+			tagEvents.Items = append(tagEvents.Items, imagev1.TagEvent{Image: image})
+		}
+		imageStream.Status.Tags = append(imageStream.Status.Tags, tagEvents)
+	}
+	// the other image stream keeps the blob app-4
+	otherImageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "lib", Namespace: testNamespace}}
+	otherImageStream.Status.Tags = []imagev1.NamedTagEventList{{Tag: "latest", Items: []imagev1.TagEvent{{Image: "sha256:lib"}}}}
+	repository, imageClient := newTestRepository([]runtime.Object{
+		imageStream,
+		otherImageStream,
+		newTestImage("sha256:lib", map[string]int64{"app-4": 40}),
+		newTestImage("sha256:kept", map[string]int64{"base": 100, "app-1": 10}),
+		newTestImage("sha256:old-1", map[string]int64{"base": 100, "app-2": 20, "shared": 5}),
+		newTestImage("sha256:older-1", map[string]int64{"base": 100, "app-3": 30}),
+		newTestImage("sha256:old-2", map[string]int64{"base": 100, "app-4": 40, "shared": 5}),
+	})
+
+	index, err := cleanup.NewBlobIndex(context.Background(), repository)
+	require.NoError(t, err)
+
+	report, err := cleanup.GetReclaimableStorage(context.Background(), index, "app", []string{"old-1", "old-2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"old-1": 55, "old-2": 0}, report.Tags)
+	assert.Equal(t, int64(55), report.Total)
+
+	_, err = cleanup.GetReclaimableStorage(context.Background(), index, "lib", []string{"latest"})
+	assert.NoError(t, err)
+	imageGets, imageStreamGets := 0, 0
+	for _, action := range imageClient.Actions() {
+		if action.Matches("get", "images") {
+			imageGets++
+		}
+		if action.Matches("get", "imagestreams") {
+			imageStreamGets++
+		}
+	}
+	assert.Equal(t, 5, imageGets, "each image is resolved once per run")
+	assert.Equal(t, 2, imageStreamGets, "the tags read for the index are reused")
+}
+
+func Test_NewBlobIndex_MissingImage(t *testing.T) {
+	imageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: testNamespace}}
+	imageStream.Status.Tags = []imagev1.NamedTagEventList{{Tag: "old", Items: []imagev1.TagEvent{{Image: "sha256:missing"}}}}
+	repository, _ := newTestRepository([]runtime.Object{imageStream})
+
+	_, err := cleanup.NewBlobIndex(context.Background(), repository)
+
+	assert.Error(t, err)
+}
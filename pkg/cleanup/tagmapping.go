@@ -0,0 +1,74 @@
+package cleanup
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// TagMappingGroupSha is the name of the group extracting the commit hash from image tags
+	TagMappingGroupSha = "sha"
+	// TagMappingGroupVersion is the name of the group extracting the git tag from image tags
+	TagMappingGroupVersion = "version"
+)
+
+// TagMapping extracts the git commit or git tag from image tags before they are compared with the git candidates. The
+// zero value compares the image tags as they are.
+type TagMapping struct {
+	// Pattern extracts the value compared with the git candidates from image tags, using the group named Group, or
+	// otherwise the first group or the whole match. Image tags not matching the pattern never match a git candidate.
+	Pattern *regexp.Regexp
+	// Group is the name of the group of the pattern, TagMappingGroupSha or TagMappingGroupVersion
+	Group string
+	// StripPrefix is removed from the git candidates and the image tags before comparing them, e.g. "v"
+	StripPrefix string
+}
+
+// IsEnabled returns true if image tags are not compared as they are
+func (m TagMapping) IsEnabled() bool {
+	return m.Pattern != nil || m.StripPrefix != ""
+}
+
+// Extract returns the value of the image tag that is compared with the git candidates. The second value is false if
+// the image tag does not match the pattern.
+func (m TagMapping) Extract(imageTag string) (string, bool) {
+	value := imageTag
+	if m.Pattern != nil {
+		match := m.Pattern.FindStringSubmatch(imageTag)
+		if match == nil {
+			return "", false
+		}
+		value = match[0]
+		if i := m.subexpIndex(); i > 0 {
+			value = match[i]
+		} else if len(match) > 1 {
+			value = match[1]
+		}
+	}
+	value = strings.TrimPrefix(value, m.StripPrefix)
+	return value, value != ""
+}
+
+// Matches evaluates if the image tag matches the git candidate. With a pattern and prefix matching, an abbreviated
+// commit hash in the image tag matches the full commit hash as well.
+func (m TagMapping) Matches(imageTag, gitValue string, matchOption MatchOption) bool {
+	if !m.IsEnabled() {
+		return match(imageTag, gitValue, matchOption)
+	}
+	value, found := m.Extract(imageTag)
+	if !found {
+		return false
+	}
+	gitValue = strings.TrimPrefix(gitValue, m.StripPrefix)
+	if m.Pattern != nil && matchOption == MatchOptionPrefix && strings.HasPrefix(gitValue, value) {
+		return true
+	}
+	return match(value, gitValue, matchOption)
+}
+
+func (m TagMapping) subexpIndex() int {
+	if m.Group == "" {
+		return -1
+	}
+	return m.Pattern.SubexpIndex(m.Group)
+}
@@ -0,0 +1,108 @@
+package cleanup
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TagMapping_Matches(t *testing.T) {
+	versionAndSha := regexp.MustCompile("^(?P<version>.+)-(?P<sha>[0-9a-f]{7})$")
+	branchShaBuild := regexp.MustCompile("^[a-z]+-(?P<sha>[0-9a-f]{7})-build[0-9]+$")
+	tests := []struct {
+		name        string
+		mapping     TagMapping
+		imageTag    string
+		gitValue    string
+		matchOption MatchOption
+		expected    bool
+	}{
+		{
+			name:        "GivenNoMapping_ThenMatchTagAsItIs",
+			imageTag:    "3f9a1c2d",
+			gitValue:    "3f9a1c2",
+			matchOption: MatchOptionPrefix,
+			expected:    true,
+		},
+		{
+			name:        "GivenShaGroup_ThenMatchAbbreviatedCommit",
+			mapping:     TagMapping{Pattern: versionAndSha, Group: TagMappingGroupSha},
+			imageTag:    "v1.4.2-3f9a1c2",
+			gitValue:    "3f9a1c2e5b7d9f1a3c5e7b9d1f3a5c7e9b1d3f5a",
+			matchOption: MatchOptionPrefix,
+			expected:    true,
+		},
+		{
+			name:        "GivenShaGroup_WhenOtherCommit_ThenNoMatch",
+			mapping:     TagMapping{Pattern: versionAndSha, Group: TagMappingGroupSha},
+			imageTag:    "v1.4.2-3f9a1c2",
+			gitValue:    "4e8b2d3e5b7d9f1a3c5e7b9d1f3a5c7e9b1d3f5a",
+			matchOption: MatchOptionPrefix,
+			expected:    false,
+		},
+		{
+			name:        "GivenVersionGroup_ThenMatchGitTag",
+			mapping:     TagMapping{Pattern: versionAndSha, Group: TagMappingGroupVersion},
+			imageTag:    "v1.4.2-3f9a1c2",
+			gitValue:    "v1.4.2",
+			matchOption: MatchOptionExact,
+			expected:    true,
+		},
+		{
+			name:        "GivenShaGroupWithSuffix_ThenMatchCommit",
+			mapping:     TagMapping{Pattern: branchShaBuild, Group: TagMappingGroupSha},
+			imageTag:    "main-3f9a1c2-build42",
+			gitValue:    "3f9a1c2e5b7d9f1a3c5e7b9d1f3a5c7e9b1d3f5a",
+			matchOption: MatchOptionPrefix,
+			expected:    true,
+		},
+		{
+			name:        "GivenNotMatchingPattern_ThenNoMatch",
+			mapping:     TagMapping{Pattern: branchShaBuild, Group: TagMappingGroupSha},
+			imageTag:    "3f9a1c2",
+			gitValue:    "3f9a1c2e5b7d9f1a3c5e7b9d1f3a5c7e9b1d3f5a",
+			matchOption: MatchOptionPrefix,
+			expected:    false,
+		},
+		{
+			name:        "GivenStripPrefix_ThenMatchGitTagWithoutPrefix",
+			mapping:     TagMapping{StripPrefix: "v"},
+			imageTag:    "1.4.2",
+			gitValue:    "v1.4.2",
+			matchOption: MatchOptionExact,
+			expected:    true,
+		},
+		{
+			name:        "GivenStripPrefix_ThenMatchImageTagWithPrefix",
+			mapping:     TagMapping{StripPrefix: "v"},
+			imageTag:    "v1.4.2",
+			gitValue:    "v1.4.2",
+			matchOption: MatchOptionExact,
+			expected:    true,
+		},
+		{
+			name:        "GivenStripPrefix_WhenExactMatch_ThenNoPartialMatch",
+			mapping:     TagMapping{StripPrefix: "v"},
+			imageTag:    "1.4",
+			gitValue:    "v1.4.2",
+			matchOption: MatchOptionExact,
+			expected:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.mapping.Matches(tt.imageTag, tt.gitValue, tt.matchOption))
+		})
+	}
+}
+
+func Test_FilterOrphanImageTags_TagMapping(t *testing.T) {
+	gitValues := []string{"3f9a1c2e5b7d9f1a3c5e7b9d1f3a5c7e9b1d3f5a"}
+	imageTags := []string{"main-3f9a1c2-build41", "main-3f9a1c2-build42", "main-4e8b2d3-build43", "latest"}
+	mapping := TagMapping{Pattern: regexp.MustCompile("^[a-z]+-([0-9a-f]{7})-build[0-9]+$"), Group: TagMappingGroupSha}
+
+	orphans := FilterOrphanImageTags(&gitValues, &imageTags, MatchOptionPrefix, mapping)
+
+	assert.Equal(t, []string{"main-4e8b2d3-build43"}, orphans)
+}
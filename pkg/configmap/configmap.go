@@ -26,6 +26,10 @@ type (
 	}
 	ServiceConfiguration struct {
 		Batch bool
+		// Resources are searched for references in addition to the predefined resources, e.g. custom resources
+		Resources []schema.GroupVersionResource
+		// ReferenceMode is either openshift.ReferenceModeStructured (default) or openshift.ReferenceModeSubstring
+		ReferenceMode string
 	}
 )
 
@@ -47,8 +51,12 @@ func (cms ConfigMapsService) List(ctx context.Context, listOptions metav1.ListOp
 }
 
 func (cms ConfigMapsService) GetUnused(ctx context.Context, namespace string, configMaps []v1.ConfigMap) (unusedConfigMaps []v1.ConfigMap, funcErr error) {
+	if cms.configuration.ReferenceMode != openshift.ReferenceModeSubstring {
+		return cms.getUnreferenced(ctx, namespace, configMaps)
+	}
 	var usedConfigMaps []v1.ConfigMap
-	funk.ForEach(openshift.PredefinedResources, func(predefinedResource schema.GroupVersionResource) {
+	searchedResources := append(append([]schema.GroupVersionResource{}, openshift.PredefinedResources...), cms.configuration.Resources...)
+	funk.ForEach(searchedResources, func(predefinedResource schema.GroupVersionResource) {
 		funk.ForEach(configMaps, func(resource v1.ConfigMap) {
 
 			resourceName := resource.GetName()
@@ -78,6 +86,24 @@ func (cms ConfigMapsService) GetUnused(ctx context.Context, namespace string, co
 	return unusedConfigMaps, funcErr
 }
 
+// getUnreferenced returns the ConfigMaps that are not referenced by any field of the searched resources
+func (cms ConfigMapsService) getUnreferenced(ctx context.Context, namespace string, configMaps []v1.ConfigMap) ([]v1.ConfigMap, error) {
+	searchedResources := append(append([]schema.GroupVersionResource{}, openshift.ConfigReferenceResources...), cms.configuration.Resources...)
+	references, err := openshift.GetConfigReferences(ctx, cms.helper, searchedResources, namespace, openshift.ConfigMapKind)
+	if err != nil {
+		return nil, err
+	}
+	var unused []v1.ConfigMap
+	for _, resource := range configMaps {
+		if resourceReferences, found := references[resource.GetName()]; found {
+			openshift.LogConfigReferences(namespace, resourceReferences)
+			continue
+		}
+		unused = append(unused, resource)
+	}
+	return unused, nil
+}
+
 func (cms ConfigMapsService) Delete(ctx context.Context, configMaps []v1.ConfigMap) error {
 	for _, resource := range configMaps {
 		err := cms.client.Delete(ctx, resource.Name, metav1.DeleteOptions{})
@@ -124,6 +150,19 @@ func (cms ConfigMapsService) FilterByMaxCount(configMaps []v1.ConfigMap, keep in
 	return configMaps[keep:]
 }
 
+// FilterPerGroup applies util.FilterPerGroup to the ConfigMaps, so that frequently changing ConfigMaps do not push out the
+// history of other groups
+func (cms ConfigMapsService) FilterPerGroup(configMaps []v1.ConfigMap, groupBy string, olderThan time.Time, keep int) (filteredResources []v1.ConfigMap) {
+	resources := make([]metav1.Object, len(configMaps))
+	for i := range configMaps {
+		resources[i] = &configMaps[i]
+	}
+	for _, resource := range util.FilterPerGroup(resources, groupBy, olderThan, keep) {
+		filteredResources = append(filteredResources, *resource.(*v1.ConfigMap))
+	}
+	return filteredResources
+}
+
 func (cms ConfigMapsService) Print(resources []v1.ConfigMap) {
 	if len(resources) == 0 {
 		log.Info("Nothing found to be deleted.")
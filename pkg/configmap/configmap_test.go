@@ -6,9 +6,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/appuio/seiso/pkg/openshift"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/fake"
@@ -30,6 +32,30 @@ func (k *HelperKubernetesErr) ResourceContains(_ context.Context, namespace, val
 	return false, errors.New("error")
 }
 
+func (k *HelperKubernetes) ListResources(_ context.Context, namespace string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	return nil, nil
+}
+
+func (k *HelperKubernetesErr) ListResources(_ context.Context, namespace string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	return nil, errors.New("error")
+}
+
+// HelperKubernetesObjects returns the objects as pods
+type HelperKubernetesObjects struct {
+	objects []unstructured.Unstructured
+}
+
+func (k *HelperKubernetesObjects) ResourceContains(_ context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error) {
+	return false, errors.New("not supported")
+}
+
+func (k *HelperKubernetesObjects) ListResources(_ context.Context, namespace string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	if resource.Resource == "pods" {
+		return k.objects, nil
+	}
+	return nil, nil
+}
+
 var testNamespace = "testNamespace"
 
 func Test_List(t *testing.T) {
@@ -149,6 +175,61 @@ func Test_FilterByMaxCount(t *testing.T) {
 	}
 }
 
+func Test_FilterPerGroup(t *testing.T) {
+	newConfigMap := func(name, component string, year int) v1.ConfigMap {
+		return v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         testNamespace,
+			Labels:            map[string]string{"component": component},
+			CreationTimestamp: metav1.Date(year, 1, 1, 0, 0, 0, 0, time.UTC),
+		}}
+	}
+	configMaps := []v1.ConfigMap{
+		newConfigMap("api-config-5d8t8h7g9k", "api", 2012),
+		newConfigMap("api-config-b2h4c6m7t8", "api", 2013),
+		newConfigMap("api-config-g9k2h8t7m6", "api", 2014),
+		newConfigMap("web-config-7h8t9k2b4c", "web", 2010),
+		newConfigMap("web-config-m6t5g4d2h9", "web", 2020),
+	}
+
+	tests := []struct {
+		name               string
+		groupBy            string
+		keep               int
+		filteredConfigMaps []string
+	}{
+		{
+			name:               "GivenNoGrouping_ThenKeepNewestOfAll",
+			keep:               2,
+			filteredConfigMaps: []string{"api-config-5d8t8h7g9k", "web-config-7h8t9k2b4c"},
+		},
+		{
+			name:               "GivenKustomizeGrouping_ThenKeepNewestPerBaseName",
+			groupBy:            "kustomize",
+			keep:               1,
+			filteredConfigMaps: []string{"api-config-b2h4c6m7t8", "api-config-5d8t8h7g9k"},
+		},
+		{
+			name:               "GivenLabelGrouping_ThenKeepNewestPerLabelValue",
+			groupBy:            "label:component",
+			keep:               2,
+			filteredConfigMaps: []string{"api-config-5d8t8h7g9k"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewConfigMapsService(nil, &HelperKubernetes{}, ServiceConfiguration{})
+			cutOffDate := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+			filtered := service.FilterPerGroup(append([]v1.ConfigMap{}, configMaps...), tt.groupBy, cutOffDate, tt.keep)
+			var names []string
+			for _, configMap := range filtered {
+				names = append(names, configMap.Name)
+			}
+			assert.Equal(t, tt.filteredConfigMaps, names)
+		})
+	}
+}
+
 func Test_Delete(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -226,12 +307,12 @@ func Test_GetUnused(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx := context.Background()
 			if tt.err == nil {
-				service := NewConfigMapsService(nil, &HelperKubernetes{}, ServiceConfiguration{Batch: false})
+				service := NewConfigMapsService(nil, &HelperKubernetes{}, ServiceConfiguration{Batch: false, ReferenceMode: openshift.ReferenceModeSubstring})
 				unused, err := service.GetUnused(ctx, testNamespace, tt.allConfigMaps)
 				assert.NoError(t, err)
 				assert.ElementsMatch(t, tt.unusedConfigMaps, unused)
 			} else {
-				service := NewConfigMapsService(nil, &HelperKubernetesErr{}, ServiceConfiguration{Batch: false})
+				service := NewConfigMapsService(nil, &HelperKubernetesErr{}, ServiceConfiguration{Batch: false, ReferenceMode: openshift.ReferenceModeSubstring})
 				unused, err := service.GetUnused(ctx, testNamespace, tt.allConfigMaps)
 				assert.Error(t, err)
 				assert.ElementsMatch(t, tt.unusedConfigMaps, unused)
@@ -240,6 +321,34 @@ func Test_GetUnused(t *testing.T) {
 	}
 }
 
+func Test_GetUnused_Structured(t *testing.T) {
+	pod := unstructured.Unstructured{Object: map[string]interface{}{
+		"kind":     "Pod",
+		"metadata": map[string]interface{}{"name": "app", "namespace": testNamespace},
+		"spec": map[string]interface{}{
+			"volumes": []interface{}{map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "nameB"}}},
+			"containers": []interface{}{map[string]interface{}{
+				"name": "app",
+				"env":  []interface{}{map[string]interface{}{"name": "NAME", "value": "nameA"}},
+			}},
+		},
+	}}
+	service := NewConfigMapsService(nil, &HelperKubernetesObjects{objects: []unstructured.Unstructured{pod}}, ServiceConfiguration{})
+
+	unused, err := service.GetUnused(context.Background(), testNamespace, generateBaseTestConfigMaps())
+
+	assert.NoError(t, err)
+	var names []string
+	for _, configMap := range unused {
+		names = append(names, configMap.Name)
+	}
+	assert.ElementsMatch(t, []string{"nameA"}, names)
+
+	service = NewConfigMapsService(nil, &HelperKubernetesErr{}, ServiceConfiguration{})
+	_, err = service.GetUnused(context.Background(), testNamespace, generateBaseTestConfigMaps())
+	assert.Error(t, err)
+}
+
 func generateBaseTestConfigMaps() []v1.ConfigMap {
 	return []v1.ConfigMap{
 		{
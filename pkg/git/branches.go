@@ -0,0 +1,60 @@
+package git
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/thoas/go-funk"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+const maxBranchSlugLength = 63
+
+var nonSlugCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// GetBranchNames returns the names of the local and remote-tracking branches of the opened repository. The remote name
+// is removed from remote-tracking branches, e.g. "origin/feature/login" becomes "feature/login". An error is returned
+// if the repository has no branches.
+func GetBranchNames(repository *git.Repository) ([]string, error) {
+	refIter, err := repository.References()
+	if err != nil {
+		return nil, err
+	}
+	defer refIter.Close()
+
+	var branches []string
+	err = refIter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		switch {
+		case name.IsBranch():
+			branches = append(branches, name.Short())
+		case name.IsRemote() && ref.Type() == plumbing.HashReference:
+			remoteBranch := strings.TrimPrefix(name.String(), "refs/remotes/")
+			if i := strings.Index(remoteBranch, "/"); i >= 0 {
+				branches = append(branches, remoteBranch[i+1:])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(branches) == 0 {
+		// without branches, every branch would be considered deleted
+		return nil, errors.New("no branches found in the repository, e.g. in a detached HEAD checkout without remote-tracking branches")
+	}
+	return funk.UniqString(branches), nil
+}
+
+// BranchSlug returns the branch name in the form CI systems use in image tags: lower case, every sequence of characters
+// other than a-z and 0-9 replaced by "-", without leading and trailing "-" and at most 63 characters long, e.g.
+// "feature/Login_Form" becomes "feature-login-form".
+func BranchSlug(branch string) string {
+	slug := nonSlugCharacters.ReplaceAllString(strings.ToLower(branch), "-")
+	if len(slug) > maxBranchSlugLength {
+		slug = slug[:maxBranchSlugLength]
+	}
+	return strings.Trim(slug, "-")
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/appuio/seiso/cfg"
+)
+
+// StdinCandidatesFile reads the candidates from stdin instead of a file
+const StdinCandidatesFile = "-"
+
+// HasRepository returns false if the candidates are read from a file or a forge API instead of a git repository
+func HasRepository(o *cfg.GitConfig) bool {
+	return o.CandidatesFile == "" && o.Forge == ""
+}
+
+// getFileCandidates reads the commit hashes or tags from the candidates file, or from stdin if the file is "-"
+func getFileCandidates(o *cfg.GitConfig) ([]string, error) {
+	reader := io.Reader(os.Stdin)
+	if o.CandidatesFile != StdinCandidatesFile {
+		file, err := os.Open(o.CandidatesFile)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+	candidates, err := readCandidates(reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not read candidates: %w", err)
+	}
+	if !o.Tag || o.SortCriteria == "" {
+		// tags are only sorted if --sort is given, so that tags which are not a version are kept by default
+		return limitCandidates(candidates, o.CommitLimit), nil
+	}
+	// The lines are expected newest first, so sorting by date keeps the order of the file
+	dates := make(map[string]time.Time, len(candidates))
+	for i, candidate := range candidates {
+		dates[candidate] = time.Unix(int64(len(candidates)-i), 0)
+	}
+	return sortAndLimitTags(candidates, SortOption(o.SortCriteria), dates, o.CommitLimit)
+}
+
+// readCandidates returns the lines of the reader, skipping empty lines and comments starting with "#"
+func readCandidates(reader io.Reader) ([]string, error) {
+	var candidates []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		candidates = append(candidates, line)
+	}
+	return candidates, scanner.Err()
+}
+
+// sortAndLimitTags sorts the tags and returns the first `limit` of them. If `limit` is 0 all tags are returned.
+func sortAndLimitTags(tags []string, sortTagBy SortOption, dates map[string]time.Time, limit int) ([]string, error) {
+	sorted, err := sortTags(tags, sortTagBy, dates)
+	if err != nil {
+		return nil, err
+	}
+	return limitCandidates(sorted, limit), nil
+}
+
+func limitCandidates(candidates []string, limit int) []string {
+	if limit > 0 && len(candidates) > limit {
+		return candidates[:limit]
+	}
+	return candidates
+}
@@ -0,0 +1,67 @@
+package git
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readCandidates(t *testing.T) {
+	candidates, err := readCandidates(strings.NewReader("# deployed commits\n3f9a1c2\n\n  8b7c6d5  \n#1a2b3c4\n"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3f9a1c2", "8b7c6d5"}, candidates)
+}
+
+func Test_GetGitCandidateList_CandidatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "candidates")
+	require.NoError(t, ioutil.WriteFile(path, []byte("v1.10.0\nnightly\nv1.9.0\nv1.2.0\n"), 0644))
+	tests := map[string]struct {
+		config   cfg.GitConfig
+		expected []string
+	}{
+		"GivenCommits_ThenReturnLinesInOrder": {
+			config:   cfg.GitConfig{CandidatesFile: path},
+			expected: []string{"v1.10.0", "nightly", "v1.9.0", "v1.2.0"},
+		},
+		"GivenCommitLimit_ThenReturnFirstLines": {
+			config:   cfg.GitConfig{CandidatesFile: path, CommitLimit: 2},
+			expected: []string{"v1.10.0", "nightly"},
+		},
+		"GivenTagsWithoutSort_ThenReturnLinesInOrder": {
+			config:   cfg.GitConfig{CandidatesFile: path, Tag: true, CommitLimit: 3},
+			expected: []string{"v1.10.0", "nightly", "v1.9.0"},
+		},
+		"GivenTagsSortedByVersion_ThenSkipInvalidVersions": {
+			config:   cfg.GitConfig{CandidatesFile: path, Tag: true, SortCriteria: "version", CommitLimit: 2},
+			expected: []string{"v1.10.0", "v1.9.0"},
+		},
+		"GivenTagsSortedByDate_ThenKeepOrderOfFile": {
+			config:   cfg.GitConfig{CandidatesFile: path, Tag: true, SortCriteria: "date"},
+			expected: []string{"v1.10.0", "nightly", "v1.9.0", "v1.2.0"},
+		},
+		"GivenTagsSortedByVersionOrDate_ThenAppendInvalidVersions": {
+			config:   cfg.GitConfig{CandidatesFile: path, Tag: true, SortCriteria: "version-or-date"},
+			expected: []string{"v1.10.0", "v1.9.0", "v1.2.0", "nightly"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			candidates, err := GetGitCandidateList(&tt.config, &cfg.GitAuthConfig{}, nil)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, candidates)
+		})
+	}
+}
+
+func Test_GetGitCandidateList_MissingCandidatesFile(t *testing.T) {
+	_, err := GetGitCandidateList(&cfg.GitConfig{CandidatesFile: filepath.Join(t.TempDir(), "missing")}, &cfg.GitAuthConfig{}, nil)
+
+	assert.Error(t, err)
+}
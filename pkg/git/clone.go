@@ -0,0 +1,111 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/appuio/seiso/cfg"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// errNoRepository is returned if the candidates are read from a repository that has not been opened
+var errNoRepository = errors.New("the git repository has not been opened")
+
+// OpenRepository opens the repository at the repo path, or clones the repository from the repo URL into memory if given
+func OpenRepository(o *cfg.GitConfig, auth *cfg.GitAuthConfig) (*git.Repository, error) {
+	if o.RepoURL == "" {
+		return git.PlainOpen(o.RepoPath)
+	}
+	authMethod, err := newAuthMethod(o.RepoURL, auth)
+	if err != nil {
+		return nil, err
+	}
+	options := &git.CloneOptions{
+		URL:   o.RepoURL,
+		Auth:  authMethod,
+		Depth: o.RepoDepth,
+		Tags:  git.AllTags,
+	}
+	if o.RepoBranch != "" {
+		options.ReferenceName = plumbing.NewBranchReferenceName(o.RepoBranch)
+		options.SingleBranch = true
+	}
+	log.WithFields(log.Fields{
+		"url":    o.RepoURL,
+		"branch": o.RepoBranch,
+		"depth":  o.RepoDepth,
+	}).Debug("Cloning git repository into memory")
+	repository, err := git.Clone(memory.NewStorage(), nil, options)
+	if err != nil {
+		return nil, fmt.Errorf("could not clone '%s': %w", o.RepoURL, err)
+	}
+	return repository, nil
+}
+
+// newAuthMethod returns the credentials for the repository URL: an SSH key for SSH URLs, a token for HTTP(S) URLs, or
+// no credentials if none are configured
+func newAuthMethod(url string, auth *cfg.GitAuthConfig) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository URL '%s': %w", url, err)
+	}
+	switch endpoint.Protocol {
+	case "ssh":
+		if auth.SSHKeyFile == "" {
+			if auth.KnownHostsFile != "" {
+				return nil, fmt.Errorf("a known hosts file requires an SSH key")
+			}
+			return nil, nil
+		}
+		user := endpoint.User
+		if user == "" {
+			user = "git"
+		}
+		keys, err := ssh.NewPublicKeysFromFile(user, auth.SSHKeyFile, auth.SSHKeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("could not read SSH key '%s': %w", auth.SSHKeyFile, err)
+		}
+		if auth.KnownHostsFile != "" {
+			// without a known hosts file, go-git reads ~/.ssh/known_hosts or the files in $SSH_KNOWN_HOSTS
+			if keys.HostKeyCallback, err = ssh.NewKnownHostsCallback(auth.KnownHostsFile); err != nil {
+				return nil, fmt.Errorf("could not read known hosts file '%s': %w", auth.KnownHostsFile, err)
+			}
+		}
+		return keys, nil
+	case "http", "https":
+		token, err := readToken(auth)
+		if err != nil {
+			return nil, err
+		}
+		if token == "" {
+			return nil, nil
+		}
+		username := auth.Username
+		if username == "" {
+			// Most providers accept any username together with an access token
+			username = "seiso"
+		}
+		return &http.BasicAuth{Username: username, Password: token}, nil
+	}
+	return nil, nil
+}
+
+// readToken returns the access token of the token file, or the token if no file is configured
+func readToken(auth *cfg.GitAuthConfig) (string, error) {
+	if auth.TokenFile == "" {
+		return auth.Token, nil
+	}
+	data, err := ioutil.ReadFile(auth.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("could not read token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
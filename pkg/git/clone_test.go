@@ -0,0 +1,164 @@
+package git
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+func Test_GetGitCandidateList_RepoURL(t *testing.T) {
+	path, master, feature, _ := newTestRepository(t)
+	url := "file://" + path
+	tests := []struct {
+		name     string
+		config   cfg.GitConfig
+		expected []string
+	}{
+		{
+			name:     "GivenRepoURL_ThenReturnCommitsOfDefaultBranch",
+			config:   cfg.GitConfig{RepoURL: url},
+			expected: master,
+		},
+		{
+			name:     "GivenRepoBranch_ThenReturnCommitsOfBranch",
+			config:   cfg.GitConfig{RepoURL: url, RepoBranch: "feature"},
+			expected: append(feature, master...),
+		},
+		{
+			name:     "GivenRepoDepth_ThenReturnLastCommits",
+			config:   cfg.GitConfig{RepoURL: url, RepoBranch: "feature", RepoDepth: 1},
+			expected: feature,
+		},
+		{
+			// origin/release/1.0 is a remote-tracking ref of the test repository, which is not cloned
+			name:     "GivenRemoteBranches_ThenReturnCommitsOfAllClonedBranches",
+			config:   cfg.GitConfig{RepoURL: url, RemoteBranches: true},
+			expected: append(feature, master...),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository, err := OpenRepository(&tt.config, &cfg.GitAuthConfig{})
+			require.NoError(t, err)
+
+			candidates, err := GetGitCandidateList(&tt.config, &cfg.GitAuthConfig{}, repository)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, candidates)
+		})
+	}
+}
+
+func Test_OpenRepository_InvalidRepoURL(t *testing.T) {
+	_, err := OpenRepository(&cfg.GitConfig{RepoURL: "file://" + filepath.Join(t.TempDir(), "missing")}, &cfg.GitAuthConfig{})
+
+	assert.Error(t, err)
+}
+
+func Test_newAuthMethod(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+	tests := []struct {
+		name     string
+		url      string
+		auth     cfg.GitAuthConfig
+		expected interface{}
+		wantErr  bool
+	}{
+		{
+			name: "GivenHTTPSWithoutToken_ThenReturnNoAuth",
+			url:  "https://git.example.com/team/app.git",
+		},
+		{
+			name:     "GivenHTTPSWithToken_ThenReturnBasicAuth",
+			url:      "https://git.example.com/team/app.git",
+			auth:     cfg.GitAuthConfig{Username: "ci", Token: "env-token"},
+			expected: &http.BasicAuth{Username: "ci", Password: "env-token"},
+		},
+		{
+			name:     "GivenHTTPSWithTokenFile_ThenPreferTokenFile",
+			url:      "https://git.example.com/team/app.git",
+			auth:     cfg.GitAuthConfig{Token: "env-token", TokenFile: tokenFile},
+			expected: &http.BasicAuth{Username: "seiso", Password: "file-token"},
+		},
+		{
+			name:    "GivenMissingTokenFile_ThenReturnError",
+			url:     "https://git.example.com/team/app.git",
+			auth:    cfg.GitAuthConfig{TokenFile: filepath.Join(t.TempDir(), "missing")},
+			wantErr: true,
+		},
+		{
+			name:    "GivenSSHWithMissingKey_ThenReturnError",
+			url:     "git@git.example.com:team/app.git",
+			auth:    cfg.GitAuthConfig{SSHKeyFile: filepath.Join(t.TempDir(), "missing")},
+			wantErr: true,
+		},
+		{
+			name: "GivenSSHWithoutKey_ThenReturnNoAuth",
+			url:  "git@git.example.com:team/app.git",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authMethod, err := newAuthMethod(tt.url, &tt.auth)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.expected == nil {
+				assert.Nil(t, authMethod)
+				return
+			}
+			assert.Equal(t, tt.expected, authMethod)
+		})
+	}
+}
+
+func Test_newAuthMethod_KnownHosts(t *testing.T) {
+	dir := t.TempDir()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "id_rsa")
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}), 0600))
+	knownHostsFile := filepath.Join(dir, "known_hosts")
+	require.NoError(t, ioutil.WriteFile(knownHostsFile, []byte("git.example.com ssh-ed25519 "+testHostKey()+"\n"), 0644))
+	url := "git@git.example.com:team/app.git"
+
+	authMethod, err := newAuthMethod(url, &cfg.GitAuthConfig{SSHKeyFile: keyFile, KnownHostsFile: knownHostsFile})
+	require.NoError(t, err)
+	keys, ok := authMethod.(*ssh.PublicKeys)
+	require.True(t, ok)
+	assert.NotNil(t, keys.HostKeyCallback)
+
+	_, err = newAuthMethod(url, &cfg.GitAuthConfig{SSHKeyFile: keyFile, KnownHostsFile: filepath.Join(dir, "missing")})
+	assert.Error(t, err, "missing known hosts file")
+	_, err = newAuthMethod(url, &cfg.GitAuthConfig{KnownHostsFile: knownHostsFile})
+	assert.Error(t, err, "known hosts file without SSH key")
+}
+
+// testHostKey returns an ed25519 public key in the base64 wire format of known_hosts files
+func testHostKey() string {
+	var wire []byte
+	for _, field := range [][]byte{[]byte("ssh-ed25519"), make([]byte, 32)} {
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(len(field)))
+		wire = append(append(wire, length...), field...)
+	}
+	return base64.StdEncoding.EncodeToString(wire)
+}
@@ -0,0 +1,104 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/util"
+	"github.com/karrick/tparse/v2"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// errPathUnchanged stops iterating the parents of a commit
+var errPathUnchanged = errors.New("path unchanged")
+
+// CommitFilter restricts the commits of the history. The zero value matches all commits.
+type CommitFilter struct {
+	// Since excludes commits committed before this time
+	Since time.Time
+	// Path excludes commits not changing the file or directory at this path, relative to the repository root
+	Path string
+}
+
+// NewCommitFilter returns the filter of the --since and --path flags. Since is a duration like "30d" or "1y2mo".
+func NewCommitFilter(o *cfg.GitConfig) (CommitFilter, error) {
+	filter := CommitFilter{Path: cleanPath(o.Path)}
+	if o.Since == "" {
+		return filter, nil
+	}
+	since, err := tparse.ParseNow(util.TimeFormat, "now-"+o.Since)
+	if err != nil {
+		return filter, fmt.Errorf("could not parse since duration %q: %w", o.Since, err)
+	}
+	filter.Since = since
+	return filter, nil
+}
+
+// IsEnabled returns true if commits are filtered at all
+func (f CommitFilter) IsEnabled() bool {
+	return !f.Since.IsZero() || f.Path != ""
+}
+
+// isTooOld returns true if the commit was committed before the Since time. Since the history is read ordered by
+// committer time, all following commits are too old as well.
+func (f CommitFilter) isTooOld(commit *object.Commit) bool {
+	return !f.Since.IsZero() && commit.Committer.When.Before(f.Since)
+}
+
+// touchesPath returns true if the commit changed the Path. Like `git log -- <path>`, merge commits are only included if
+// they differ from each of their parents. Commits without (readable) parents, e.g. in shallow clones, touch the Path if
+// it exists.
+func (f CommitFilter) touchesPath(commit *object.Commit) (bool, error) {
+	if f.Path == "" {
+		return true, nil
+	}
+	hash, err := pathHash(commit, f.Path)
+	if err != nil {
+		return false, err
+	}
+	parents := 0
+	err = commit.Parents().ForEach(func(parent *object.Commit) error {
+		parentHash, err := pathHash(parent, f.Path)
+		if err != nil {
+			return err
+		}
+		if parentHash == hash {
+			return errPathUnchanged
+		}
+		parents++
+		return nil
+	})
+	if err == errPathUnchanged {
+		return false, nil
+	}
+	if err != nil && err != plumbing.ErrObjectNotFound {
+		return false, err
+	}
+	return parents > 0 || hash != plumbing.ZeroHash, nil
+}
+
+// pathHash returns the hash of the file or directory at the path, or the zero hash if it does not exist in the commit
+func pathHash(commit *object.Commit, filePath string) (plumbing.Hash, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	entry, err := tree.FindEntry(filePath)
+	if err == object.ErrEntryNotFound || err == object.ErrDirectoryNotFound {
+		return plumbing.ZeroHash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return entry.Hash, nil
+}
+
+// cleanPath normalizes the path to the form used in git trees, e.g. "./services/api/" to "services/api"
+func cleanPath(filePath string) string {
+	return strings.Trim(path.Clean("/"+filePath), "/")
+}
@@ -0,0 +1,141 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// newTestMonorepo creates a repository with one commit per day, each changing the file of the given component
+func newTestMonorepo(t *testing.T, components ...string) (path string, commits []string) {
+	path = t.TempDir()
+	repository, err := git.PlainInit(path, false)
+	require.NoError(t, err)
+	worktree, err := repository.Worktree()
+	require.NoError(t, err)
+
+	when := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, component := range components {
+		require.NoError(t, os.MkdirAll(filepath.Join(path, component), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, component, "file"), []byte(string(rune('a'+i))), 0644))
+		_, err := worktree.Add(filepath.Join(component, "file"))
+		require.NoError(t, err)
+		hash, err := worktree.Commit(component, &git.CommitOptions{Author: &object.Signature{Name: "test", When: when}})
+		require.NoError(t, err)
+		commits = append([]string{hash.String()}, commits...)
+		when = when.Add(24 * time.Hour)
+	}
+	return path, commits
+}
+
+func Test_GetCommitHashes_Filter(t *testing.T) {
+	path, commits := newTestMonorepo(t, "services/api", "services/web", "services/api", "docs", "services/api")
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	tests := map[string]struct {
+		filter      CommitFilter
+		commitLimit int
+		expected    []string
+	}{
+		"GivenNoFilter_ThenReturnAllCommits": {
+			expected: commits,
+		},
+		"GivenPath_ThenReturnCommitsChangingPath": {
+			filter:   CommitFilter{Path: "services/api"},
+			expected: []string{commits[0], commits[2], commits[4]},
+		},
+		"GivenParentPath_ThenReturnCommitsChangingSubtree": {
+			filter:   CommitFilter{Path: "services"},
+			expected: []string{commits[0], commits[2], commits[3], commits[4]},
+		},
+		"GivenFilePath_ThenReturnCommitsChangingFile": {
+			filter:   CommitFilter{Path: "services/web/file"},
+			expected: []string{commits[3]},
+		},
+		"GivenMissingPath_ThenReturnNoCommits": {
+			filter: CommitFilter{Path: "services/db"},
+		},
+		"GivenSince_ThenReturnNewerCommits": {
+			filter:   CommitFilter{Since: day(3)},
+			expected: commits[:3],
+		},
+		"GivenSinceAndPath_ThenReturnNewerCommitsChangingPath": {
+			filter:   CommitFilter{Since: day(3), Path: "services/api"},
+			expected: []string{commits[0], commits[2]},
+		},
+		"GivenCommitLimit_ThenOnlyCountMatchingCommits": {
+			filter:      CommitFilter{Path: "services/api"},
+			commitLimit: 2,
+			expected:    []string{commits[0], commits[2]},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			commitHashes, err := GetCommitHashes(path, tt.commitLimit, tt.filter)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, commitHashes)
+		})
+	}
+}
+
+func Test_GetCommitHashesOfRefs_Filter(t *testing.T) {
+	path, master, feature, _ := newTestRepository(t)
+
+	commitHashes, err := GetCommitHashesOfRefs(path, []string{LocalBranchesPattern}, 0, CommitFilter{
+		Since: time.Date(2021, 1, 1, 1, 30, 0, 0, time.UTC),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{feature[0], master[0]}, commitHashes)
+}
+
+func Test_NewCommitFilter(t *testing.T) {
+	tests := map[string]struct {
+		config        cfg.GitConfig
+		expectedPath  string
+		expectedSince time.Duration
+		expectErr     bool
+	}{
+		"GivenNoFlags_ThenDisableFilter": {},
+		"GivenPath_ThenCleanPath": {
+			config:       cfg.GitConfig{Path: "./services/api/"},
+			expectedPath: "services/api",
+		},
+		"GivenRootPath_ThenDisablePathFilter": {
+			config: cfg.GitConfig{Path: "."},
+		},
+		"GivenSince_ThenSubtractDuration": {
+			config:        cfg.GitConfig{Since: "2d"},
+			expectedSince: 48 * time.Hour,
+		},
+		"GivenInvalidSince_ThenReturnError": {
+			config:    cfg.GitConfig{Since: "two days"},
+			expectErr: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			filter, err := NewCommitFilter(&tt.config)
+
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPath, filter.Path)
+			if tt.expectedSince == 0 {
+				assert.True(t, filter.Since.IsZero())
+			} else {
+				assert.WithinDuration(t, time.Now().Add(-tt.expectedSince), filter.Since, time.Minute)
+			}
+		})
+	}
+}
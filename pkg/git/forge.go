@@ -0,0 +1,370 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/appuio/seiso/pkg/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ForgeGitHub reads the commits and tags of a GitHub repository
+	ForgeGitHub = "github"
+	// ForgeGitLab reads the commits and tags of a GitLab project
+	ForgeGitLab = "gitlab"
+
+	defaultGitHubURL = "https://api.github.com"
+	defaultGitLabURL = "https://gitlab.com"
+	forgePageSize    = 100
+)
+
+type (
+	// forge lists the commits and tags of a project with the REST API of a git hosting service
+	forge interface {
+		// commits returns the commit hashes of the ref (or the default branch) matching the filter, newest first
+		commits(ctx context.Context, ref string, filter CommitFilter, limit int) ([]string, error)
+		// tags returns the tags with their commit and, if requested, their date
+		tags(ctx context.Context, withDates bool) ([]forgeTag, error)
+	}
+	forgeTag struct {
+		name   string
+		commit string
+		date   time.Time
+	}
+	apiClient struct {
+		baseURL    *url.URL
+		httpClient *http.Client
+		headers    map[string]string
+	}
+	gitHub struct {
+		*apiClient
+		project string
+	}
+	gitLab struct {
+		*apiClient
+		project string
+	}
+	gitHubCommit struct {
+		SHA    string `json:"sha"`
+		Commit struct {
+			Committer struct {
+				Date time.Time `json:"date"`
+			} `json:"committer"`
+		} `json:"commit"`
+	}
+	gitHubTag struct {
+		Name   string `json:"name"`
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+	}
+	gitLabCommit struct {
+		ID            string    `json:"id"`
+		CommittedDate time.Time `json:"committed_date"`
+	}
+	gitLabTag struct {
+		Name      string       `json:"name"`
+		CreatedAt *time.Time   `json:"created_at"`
+		Commit    gitLabCommit `json:"commit"`
+	}
+)
+
+// IsValidForge returns true if the forge is supported
+func IsValidForge(forge string) bool {
+	return forge == ForgeGitHub || forge == ForgeGitLab
+}
+
+// getForgeCandidates reads the commit hashes or tags of the project from the forge API
+func getForgeCandidates(o *cfg.GitConfig, auth *cfg.GitAuthConfig, filter CommitFilter) ([]string, error) {
+	client, err := newForge(o, auth)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if !o.Tag {
+		return client.commits(ctx, o.RepoBranch, filter, o.CommitLimit)
+	}
+	sortTagBy := sortOptionOf(o)
+	tags, err := client.tags(ctx, sortTagBy == SortOptionDate || sortTagBy == SortOptionVersionOrDate)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(tags))
+	dates := map[string]time.Time{}
+	for _, tag := range tags {
+		names = append(names, tag.name)
+		dates[tag.name] = tag.date
+	}
+	return sortAndLimitTags(names, sortTagBy, dates, o.CommitLimit)
+}
+
+// getForgeTaggedCommits returns the commit hashes of the tags of the project by tag name
+func getForgeTaggedCommits(o *cfg.GitConfig, auth *cfg.GitAuthConfig) (map[string]string, error) {
+	client, err := newForge(o, auth)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := client.tags(context.Background(), false)
+	if err != nil {
+		return nil, err
+	}
+	commits := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		commits[tag.name] = tag.commit
+	}
+	return commits, nil
+}
+
+// newForge returns the API client of the forge. The token is sent as bearer token to GitHub and as private token to
+// GitLab.
+func newForge(o *cfg.GitConfig, auth *cfg.GitAuthConfig) (forge, error) {
+	if o.ForgeProject == "" {
+		return nil, fmt.Errorf("no project of the forge given")
+	}
+	token, err := readToken(auth)
+	if err != nil {
+		return nil, err
+	}
+	switch o.Forge {
+	case ForgeGitHub:
+		client, err := newAPIClient(o.ForgeURL, defaultGitHubURL, map[string]string{
+			"Accept":        "application/vnd.github.v3+json",
+			"Authorization": bearer(token),
+		})
+		return &gitHub{apiClient: client, project: o.ForgeProject}, err
+	case ForgeGitLab:
+		client, err := newAPIClient(o.ForgeURL, defaultGitLabURL, map[string]string{"PRIVATE-TOKEN": token})
+		return &gitLab{apiClient: client, project: o.ForgeProject}, err
+	}
+	return nil, fmt.Errorf("unsupported forge: %s", o.Forge)
+}
+
+func bearer(token string) string {
+	if token == "" {
+		return ""
+	}
+	return "Bearer " + token
+}
+
+func newAPIClient(apiURL, defaultURL string, headers map[string]string) (*apiClient, error) {
+	if apiURL == "" {
+		apiURL = defaultURL
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(apiURL, "/") + "/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid forge URL: %w", err)
+	}
+	if baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid forge URL %q: missing host", apiURL)
+	}
+	return &apiClient{baseURL: baseURL, httpClient: http.DefaultClient, headers: headers}, nil
+}
+
+func (g *gitHub) commits(ctx context.Context, ref string, filter CommitFilter, limit int) ([]string, error) {
+	query := commitQuery(filter, limit)
+	if ref != "" {
+		query.Set("sha", ref)
+	}
+	var hashes []string
+	err := g.list(ctx, g.repoPath("commits"), query, limit, func(body io.Reader) (int, error) {
+		var page []gitHubCommit
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return 0, err
+		}
+		for _, commit := range page {
+			hashes = append(hashes, commit.SHA)
+		}
+		return len(page), nil
+	})
+	return limitCandidates(hashes, limit), err
+}
+
+// tags of GitHub do not include a date, so the committer date of each tagged commit is requested if needed
+func (g *gitHub) tags(ctx context.Context, withDates bool) ([]forgeTag, error) {
+	var tags []gitHubTag
+	err := g.list(ctx, g.repoPath("tags"), pageQuery(0), 0, func(body io.Reader) (int, error) {
+		var page []gitHubTag
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return 0, err
+		}
+		tags = append(tags, page...)
+		return len(page), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]forgeTag, 0, len(tags))
+	for _, tag := range tags {
+		info := forgeTag{name: tag.Name, commit: tag.Commit.SHA}
+		if withDates {
+			commit := gitHubCommit{}
+			if err := g.get(ctx, g.repoPath("commits/"+tag.Commit.SHA), nil, &commit); err != nil {
+				return nil, err
+			}
+			info.date = commit.Commit.Committer.Date
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+func (g *gitHub) repoPath(resource string) string {
+	return "repos/" + strings.Trim(g.project, "/") + "/" + resource
+}
+
+func (g *gitLab) commits(ctx context.Context, ref string, filter CommitFilter, limit int) ([]string, error) {
+	query := commitQuery(filter, limit)
+	if ref != "" {
+		query.Set("ref_name", ref)
+	}
+	var hashes []string
+	err := g.list(ctx, g.projectPath("repository/commits"), query, limit, func(body io.Reader) (int, error) {
+		var page []gitLabCommit
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return 0, err
+		}
+		for _, commit := range page {
+			hashes = append(hashes, commit.ID)
+		}
+		return len(page), nil
+	})
+	return limitCandidates(hashes, limit), err
+}
+
+// tags of GitLab include the date of annotated tags and the date of the tagged commit
+func (g *gitLab) tags(ctx context.Context, _ bool) ([]forgeTag, error) {
+	var result []forgeTag
+	err := g.list(ctx, g.projectPath("repository/tags"), pageQuery(0), 0, func(body io.Reader) (int, error) {
+		var page []gitLabTag
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return 0, err
+		}
+		for _, tag := range page {
+			info := forgeTag{name: tag.Name, commit: tag.Commit.ID, date: tag.Commit.CommittedDate}
+			if tag.CreatedAt != nil {
+				info.date = *tag.CreatedAt
+			}
+			result = append(result, info)
+		}
+		return len(page), nil
+	})
+	return result, err
+}
+
+func (g *gitLab) projectPath(resource string) string {
+	return "api/v4/projects/" + url.PathEscape(strings.Trim(g.project, "/")) + "/" + resource
+}
+
+// pageQuery returns the page size parameter of GitHub and GitLab, reading no more than `limit` items per page
+func pageQuery(limit int) url.Values {
+	pageSize := forgePageSize
+	if limit > 0 && limit < pageSize {
+		pageSize = limit
+	}
+	return url.Values{"per_page": []string{strconv.Itoa(pageSize)}}
+}
+
+// commitQuery returns the query parameters common to the commit APIs of GitHub and GitLab
+func commitQuery(filter CommitFilter, limit int) url.Values {
+	query := pageQuery(limit)
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.UTC().Format(time.RFC3339))
+	}
+	if filter.Path != "" {
+		query.Set("path", filter.Path)
+	}
+	return query
+}
+
+// list requests the pages of the resource by following the "next" links, until `limit` items are read. If `limit` is
+// 0, all pages are read.
+func (c *apiClient) list(ctx context.Context, path string, query url.Values, limit int, decode func(io.Reader) (int, error)) error {
+	target, err := c.baseURL.Parse(path)
+	if err != nil {
+		return err
+	}
+	target.RawQuery = query.Encode()
+	for count := 0; target != nil && (limit <= 0 || count < limit); {
+		resp, err := c.send(ctx, target.String())
+		if err != nil {
+			return err
+		}
+		read, err := decode(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("could not decode response of %s: %w", target.Path, err)
+		}
+		count += read
+		if target, err = c.nextPage(target, resp.Header.Get("Link")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextPage returns the URL of the "next" link relative to the current page, or nil on the last page. Links to another
+// scheme or host than the forge URL are rejected, so that the token is never sent elsewhere.
+func (c *apiClient) nextPage(current *url.URL, header string) (*url.URL, error) {
+	link := util.NextLink(header)
+	if link == "" {
+		return nil, nil
+	}
+	next, err := current.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid next link %q: %w", link, err)
+	}
+	if next.Scheme != c.baseURL.Scheme || next.Host != c.baseURL.Host {
+		return nil, fmt.Errorf("refusing to follow next link to %s://%s outside of the forge URL", next.Scheme, next.Host)
+	}
+	return next, nil
+}
+
+func (c *apiClient) get(ctx context.Context, path string, query url.Values, into interface{}) error {
+	target, err := c.baseURL.Parse(path)
+	if err != nil {
+		return err
+	}
+	target.RawQuery = query.Encode()
+	resp, err := c.send(ctx, target.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		return fmt.Errorf("could not decode response of %s: %w", target.Path, err)
+	}
+	return nil
+}
+
+func (c *apiClient) send(ctx context.Context, target string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range c.headers {
+		if value != "" {
+			req.Header.Set(key, value)
+		}
+	}
+	log.WithField("url", target).Debug("Sending forge request")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s failed: %s", req.URL.Path, resp.Status)
+	}
+	return resp, nil
+}
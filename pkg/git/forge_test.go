@@ -0,0 +1,268 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appuio/seiso/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret"
+
+type fakeForge struct {
+	server *httptest.Server
+	// commits are the commit hashes of the default branch, newest first
+	commits []string
+	// tags maps tag names to the tagged commit
+	tags  map[string]string
+	dates map[string]time.Time
+	// queries records the query of the last commits request
+	queries url.Values
+	// nextURL is the URL the "next" links point to, the forge itself if empty
+	nextURL string
+}
+
+func newFakeForge(t *testing.T) *fakeForge {
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	f := &fakeForge{
+		commits: []string{"c5", "c4", "c3", "c2", "c1"},
+		tags:    map[string]string{"v1.0.0": "c1", "v1.1.0": "c3", "nightly": "c5"},
+		dates:   map[string]time.Time{"c1": day(1), "c2": day(2), "c3": day(3), "c4": day(4), "c5": day(5)},
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeForge) serve(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.EscapedPath()
+	switch {
+	case strings.HasPrefix(path, "/repos/team/app/"):
+		if req.Header.Get("Authorization") != "Bearer "+testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		resource := strings.TrimPrefix(path, "/repos/team/app/")
+		switch {
+		case resource == "commits":
+			f.queries = req.URL.Query()
+			f.servePage(w, req, f.commits, func(sha string) interface{} {
+				return map[string]interface{}{"sha": sha, "commit": map[string]interface{}{"committer": map[string]interface{}{"date": f.dates[sha]}}}
+			})
+		case strings.HasPrefix(resource, "commits/"):
+			sha := strings.TrimPrefix(resource, "commits/")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"sha": sha, "commit": map[string]interface{}{"committer": map[string]interface{}{"date": f.dates[sha]}}})
+		case resource == "tags":
+			f.servePage(w, req, f.tagNames(), func(name string) interface{} {
+				return map[string]interface{}{"name": name, "commit": map[string]interface{}{"sha": f.tags[name]}}
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case strings.HasPrefix(path, "/api/v4/projects/team%2Fapp/repository/"):
+		if req.Header.Get("PRIVATE-TOKEN") != testToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch strings.TrimPrefix(path, "/api/v4/projects/team%2Fapp/repository/") {
+		case "commits":
+			f.queries = req.URL.Query()
+			f.servePage(w, req, f.commits, func(sha string) interface{} {
+				return map[string]interface{}{"id": sha, "committed_date": f.dates[sha]}
+			})
+		case "tags":
+			f.servePage(w, req, f.tagNames(), func(name string) interface{} {
+				tag := map[string]interface{}{"name": name, "commit": map[string]interface{}{"id": f.tags[name], "committed_date": f.dates[f.tags[name]]}}
+				if name == "v1.0.0" {
+					// annotated tag created after the newer commits
+					tag["created_at"] = time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+				}
+				return tag
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// servePage serves a page of the items according to the "page" and "per_page" parameters, with a Link header to the
+// next page. Like the real APIs, the page size is capped, here at 2 items to test the pagination.
+func (f *fakeForge) servePage(w http.ResponseWriter, req *http.Request, items []string, render func(string) interface{}) {
+	perPage, _ := strconv.Atoi(req.URL.Query().Get("per_page"))
+	if perPage > 2 {
+		perPage = 2
+	}
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	start, end := (page-1)*perPage, page*perPage
+	if end >= len(items) {
+		end = len(items)
+	} else {
+		next := *req.URL
+		query := next.Query()
+		query.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = query.Encode()
+		nextURL := f.nextURL
+		if nextURL == "" {
+			nextURL = f.server.URL
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="next"`, nextURL, next.String()))
+	}
+	rendered := []interface{}{}
+	for _, item := range items[start:end] {
+		rendered = append(rendered, render(item))
+	}
+	_ = json.NewEncoder(w).Encode(rendered)
+}
+
+func (f *fakeForge) tagNames() []string {
+	return []string{"nightly", "v1.0.0", "v1.1.0"}
+}
+
+func Test_GetGitCandidateList_Forge(t *testing.T) {
+	f := newFakeForge(t)
+	auth := &cfg.GitAuthConfig{Token: testToken}
+	for _, forge := range []string{ForgeGitHub, ForgeGitLab} {
+		config := cfg.GitConfig{Forge: forge, ForgeURL: f.server.URL, ForgeProject: "team/app"}
+		tests := map[string]struct {
+			config   cfg.GitConfig
+			expected []string
+		}{
+			"GivenCommits_ThenReturnAllPages": {
+				config:   config,
+				expected: f.commits,
+			},
+			"GivenCommitLimit_ThenStopReading": {
+				config:   withGit(config, func(c *cfg.GitConfig) { c.CommitLimit = 3 }),
+				expected: []string{"c5", "c4", "c3"},
+			},
+			"GivenTagsSortedByVersion_ThenSkipInvalidVersions": {
+				config:   withGit(config, func(c *cfg.GitConfig) { c.Tag, c.SortCriteria = true, "version" }),
+				expected: []string{"v1.1.0", "v1.0.0"},
+			},
+			"GivenTagsSortedByVersionOrDate_ThenAppendInvalidVersions": {
+				config:   withGit(config, func(c *cfg.GitConfig) { c.Tag, c.SortCriteria = true, "version-or-date" }),
+				expected: []string{"v1.1.0", "v1.0.0", "nightly"},
+			},
+			"GivenTagLimit_ThenReturnFirstSortedTags": {
+				config:   withGit(config, func(c *cfg.GitConfig) { c.Tag, c.SortCriteria, c.CommitLimit = true, "alphabetic", 2 }),
+				expected: []string{"nightly", "v1.0.0"},
+			},
+			"GivenTagLimit_ThenSortBeforeLimit": {
+				config:   withGit(config, func(c *cfg.GitConfig) { c.Tag, c.SortCriteria, c.CommitLimit = true, "version", 2 }),
+				expected: []string{"v1.1.0", "v1.0.0"},
+			},
+		}
+		for name, tt := range tests {
+			t.Run(forge+"/"+name, func(t *testing.T) {
+				candidates, err := GetGitCandidateList(&tt.config, auth, nil)
+
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, candidates)
+			})
+		}
+	}
+}
+
+func Test_GetGitCandidateList_ForgeTagsSortedByDate(t *testing.T) {
+	f := newFakeForge(t)
+	tests := map[string][]string{
+		ForgeGitHub: {"nightly", "v1.1.0", "v1.0.0"},
+		// GitLab returns the date of the annotated tag v1.0.0
+		ForgeGitLab: {"v1.0.0", "nightly", "v1.1.0"},
+	}
+	for forge, expected := range tests {
+		t.Run(forge, func(t *testing.T) {
+			config := cfg.GitConfig{Forge: forge, ForgeURL: f.server.URL, ForgeProject: "team/app", Tag: true, SortCriteria: "date"}
+
+			candidates, err := GetGitCandidateList(&config, &cfg.GitAuthConfig{Token: testToken}, nil)
+
+			assert.NoError(t, err)
+			assert.Equal(t, expected, candidates)
+		})
+	}
+}
+
+func Test_GetTaggedCommits_Forge(t *testing.T) {
+	f := newFakeForge(t)
+	for _, forge := range []string{ForgeGitHub, ForgeGitLab} {
+		t.Run(forge, func(t *testing.T) {
+			config := cfg.GitConfig{Forge: forge, ForgeURL: f.server.URL, ForgeProject: "team/app"}
+
+			commits, err := GetTaggedCommits(&config, &cfg.GitAuthConfig{Token: testToken}, nil)
+
+			assert.NoError(t, err)
+			assert.Equal(t, f.tags, commits)
+		})
+	}
+}
+
+func Test_GetGitCandidateList_ForgeFilter(t *testing.T) {
+	f := newFakeForge(t)
+	tests := map[string]string{ForgeGitHub: "sha", ForgeGitLab: "ref_name"}
+	for forge, refParameter := range tests {
+		t.Run(forge, func(t *testing.T) {
+			config := cfg.GitConfig{Forge: forge, ForgeURL: f.server.URL, ForgeProject: "team/app", RepoBranch: "main", Path: "./services/api/", Since: "1d"}
+
+			_, err := GetGitCandidateList(&config, &cfg.GitAuthConfig{Token: testToken}, nil)
+
+			require.NoError(t, err)
+			assert.Equal(t, "main", f.queries.Get(refParameter))
+			assert.Equal(t, "services/api", f.queries.Get("path"))
+			since, err := time.Parse(time.RFC3339, f.queries.Get("since"))
+			assert.NoError(t, err)
+			assert.WithinDuration(t, time.Now().Add(-24*time.Hour), since, time.Minute)
+		})
+	}
+}
+
+func Test_GetGitCandidateList_ForgeUnauthorized(t *testing.T) {
+	f := newFakeForge(t)
+	for _, forge := range []string{ForgeGitHub, ForgeGitLab} {
+		t.Run(forge, func(t *testing.T) {
+			config := cfg.GitConfig{Forge: forge, ForgeURL: f.server.URL, ForgeProject: "team/app"}
+
+			_, err := GetGitCandidateList(&config, &cfg.GitAuthConfig{Token: "invalid"}, nil)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func withGit(config cfg.GitConfig, modify func(*cfg.GitConfig)) cfg.GitConfig {
+	modify(&config)
+	return config
+}
+
+func Test_GetGitCandidateList_ForgeForeignNextLink(t *testing.T) {
+	f := newFakeForge(t)
+	var foreignRequests int
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		foreignRequests++
+	}))
+	t.Cleanup(foreign.Close)
+	f.nextURL = foreign.URL
+	for _, forge := range []string{ForgeGitHub, ForgeGitLab} {
+		t.Run(forge, func(t *testing.T) {
+			config := cfg.GitConfig{Forge: forge, ForgeURL: f.server.URL, ForgeProject: "team/app"}
+
+			_, err := GetGitCandidateList(&config, &cfg.GitAuthConfig{Token: testToken}, nil)
+
+			assert.Error(t, err)
+			assert.Zero(t, foreignRequests)
+		})
+	}
+}
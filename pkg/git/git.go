@@ -4,28 +4,40 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/appuio/seiso/cfg"
+	log "github.com/sirupsen/logrus"
 
 	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 // GetCommitHashes returns the commit hashes of a given repository ordered by the `git.LogOrderCommitterTime`. If `commitLimit` is 0 all commits will be returned.
-func GetCommitHashes(repoPath string, commitLimit int) ([]string, error) {
-	var commitHashes []string
-
+// Only commits matching the filter are returned and counted for the limit.
+func GetCommitHashes(repoPath string, commitLimit int, filter CommitFilter) ([]string, error) {
 	repository, err := git.PlainOpen(repoPath)
 	if err != nil {
 		return nil, err
 	}
+	return getCommitHashes(repository, commitLimit, filter)
+}
 
-	commitIter, err := repository.Log(&git.LogOptions{Order: git.LogOrderCommitterTime})
-	defer commitIter.Close()
+func getCommitHashes(repository *git.Repository, commitLimit int, filter CommitFilter) ([]string, error) {
+	var commitHashes []string
+
+	head, err := repository.Head()
+	if err != nil {
+		return nil, err
+	}
+	commitIter, err := logCommits(repository, head.Hash())
 	if err != nil {
 		return nil, err
 	}
+	defer commitIter.Close()
 
-	for i := 0; i < commitLimit || commitLimit == 0; i++ {
+	for i := 0; i < commitLimit || commitLimit == 0; {
 		commit, err := commitIter.Next()
 		if err != nil {
 			if err == io.EOF {
@@ -33,30 +45,70 @@ func GetCommitHashes(repoPath string, commitLimit int) ([]string, error) {
 			}
 			return nil, err
 		}
-		commitHashes = append(commitHashes, commit.Hash.String())
+		if filter.isTooOld(commit) {
+			break
+		}
+		touched, err := filter.touchesPath(commit)
+		if err != nil {
+			return nil, err
+		}
+		if touched {
+			commitHashes = append(commitHashes, commit.Hash.String())
+			i++
+		}
 	}
 
 	return commitHashes, nil
 }
 
-// GetTags returns the commit tags of a given repository ordered alphabetically or by version. If `commitLimit` is 0 all tags will be returned.
-func GetTags(repoPath string, tagLimit int, sortTagBy SortOption) ([]string, error) {
-	var commitTags []string
+// logCommits returns the commits reachable from the hash ordered by committer time. In shallow clones, the walk stops at
+// the shallow commits instead of failing on their missing parents.
+func logCommits(repository *git.Repository, from plumbing.Hash) (object.CommitIter, error) {
+	commit, err := repository.CommitObject(from)
+	if err != nil {
+		return nil, err
+	}
+	shallowCommits, err := repository.Storer.Shallow()
+	if err != nil {
+		return nil, err
+	}
+	var missingParents []plumbing.Hash
+	for _, hash := range shallowCommits {
+		if shallowCommit, err := repository.CommitObject(hash); err == nil {
+			missingParents = append(missingParents, shallowCommit.ParentHashes...)
+		}
+	}
+	return object.NewCommitIterCTime(commit, nil, missingParents), nil
+}
 
+// GetTags returns the commit tags of a given repository ordered alphabetically, by version or by date. If `tagLimit` is
+// 0 all tags will be returned. Sorted by version or alphabetically, the first `tagLimit` tags of the repository are
+// sorted. Sorted by date or version-or-date, all tags are sorted before the first `tagLimit` of them are returned, as
+// the order of the repository is unrelated to the dates of the tags.
+func GetTags(repoPath string, tagLimit int, sortTagBy SortOption) ([]string, error) {
 	repository, err := git.PlainOpen(repoPath)
 	if err != nil {
 		return nil, err
 	}
+	return getTags(repository, tagLimit, sortTagBy)
+}
+
+func getTags(repository *git.Repository, tagLimit int, sortTagBy SortOption) ([]string, error) {
+	var commitTags []string
+	tagDates := map[string]time.Time{}
 
 	tagIter, err := repository.Tags()
-	defer tagIter.Close()
 	if err != nil {
 		return nil, err
 	}
+	defer tagIter.Close()
 
-	for i := 0; i < tagLimit || tagLimit == 0; i++ {
+	iterLimit := tagLimit
+	if sortTagBy == SortOptionDate || sortTagBy == SortOptionVersionOrDate {
+		iterLimit = 0
+	}
+	for i := 0; i < iterLimit || iterLimit == 0; i++ {
 		tag, err := tagIter.Next()
-
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -64,28 +116,120 @@ func GetTags(repoPath string, tagLimit int, sortTagBy SortOption) ([]string, err
 			return nil, err
 		}
 
-		splittedPath := strings.Split(tag.Name().String(), "/")
-		tagName := splittedPath[len(splittedPath)-1]
-
+		tagName := shortTagName(tag)
 		commitTags = append(commitTags, tagName)
+		tagDates[tagName] = tagDate(repository, tag)
 	}
 
-	return sortTags(commitTags, sortTagBy)
+	return sortAndLimitTags(commitTags, sortTagBy, tagDates, tagLimit)
 }
 
-// GetGitCandidateList returns either git tags or git commit SHAs
-func GetGitCandidateList(o *cfg.GitConfig) ([]string, error) {
+// shortTagName returns the last segment of the tag name, e.g. "1.0" of "refs/tags/release/1.0"
+func shortTagName(tag *plumbing.Reference) string {
+	splittedPath := strings.Split(tag.Name().String(), "/")
+	return splittedPath[len(splittedPath)-1]
+}
+
+// GetTaggedCommits returns the commit hashes the git tags point to by tag name, read from the opened repository or the
+// forge. Annotated tags are resolved to their commit, tags of other objects are skipped.
+func GetTaggedCommits(o *cfg.GitConfig, auth *cfg.GitAuthConfig, repository *git.Repository) (map[string]string, error) {
+	if o.Forge != "" {
+		return getForgeTaggedCommits(o, auth)
+	}
+	if repository == nil {
+		return nil, errNoRepository
+	}
+	return getTaggedCommits(repository)
+}
+
+func getTaggedCommits(repository *git.Repository) (map[string]string, error) {
+	tagIter, err := repository.Tags()
+	if err != nil {
+		return nil, err
+	}
+	defer tagIter.Close()
+	commits := map[string]string{}
+	err = tagIter.ForEach(func(tag *plumbing.Reference) error {
+		hash := tag.Hash()
+		if tagObject, err := repository.TagObject(hash); err == nil {
+			commit, err := tagObject.Commit()
+			if err != nil {
+				log.WithError(err).WithField("tag", tag.Name().String()).Debug("Skipped tag not pointing to a commit")
+				return nil
+			}
+			hash = commit.Hash
+		} else if _, err := repository.CommitObject(hash); err != nil {
+			log.WithError(err).WithField("tag", tag.Name().String()).Debug("Skipped tag not pointing to a commit")
+			return nil
+		}
+		commits[shortTagName(tag)] = hash.String()
+		return nil
+	})
+	return commits, err
+}
+
+// tagDate returns the tagger date of an annotated tag or the committer date of the commit of a lightweight tag. Tags
+// pointing to other objects have no date.
+func tagDate(repository *git.Repository, ref *plumbing.Reference) time.Time {
+	if tag, err := repository.TagObject(ref.Hash()); err == nil {
+		return tag.Tagger.When
+	}
+	commit, err := repository.CommitObject(ref.Hash())
+	if err != nil {
+		log.WithError(err).WithField("tag", ref.Name().String()).Debug("Could not determine date of tag")
+		return time.Time{}
+	}
+	return commit.Committer.When
+}
+
+// sortOptionOf returns the sort option of the config, or the DefaultSortOption if none is given
+func sortOptionOf(o *cfg.GitConfig) SortOption {
+	if o.SortCriteria == "" {
+		return DefaultSortOption
+	}
+	return SortOption(o.SortCriteria)
+}
+
+// GetGitCandidateList returns either git tags or git commit SHAs of the opened repository, of the lines of
+// --candidates-file or of the project on GitHub or GitLab given by --forge. The repository is opened with
+// OpenRepository and may be nil if the candidates are read from a file or a forge.
+func GetGitCandidateList(o *cfg.GitConfig, auth *cfg.GitAuthConfig, repository *git.Repository) ([]string, error) {
+	filter, err := NewCommitFilter(o)
+	if err != nil {
+		return []string{}, err
+	}
+	if o.CandidatesFile != "" {
+		candidates, err := getFileCandidates(o)
+		if err != nil {
+			return []string{}, fmt.Errorf("reading candidates from '%s' failed: %w", o.CandidatesFile, err)
+		}
+		return candidates, nil
+	}
+	if o.Forge != "" {
+		candidates, err := getForgeCandidates(o, auth, filter)
+		if err != nil {
+			return []string{}, fmt.Errorf("reading candidates of '%s' from %s failed: %w", o.ForgeProject, o.Forge, err)
+		}
+		return candidates, nil
+	}
+	if repository == nil {
+		return []string{}, errNoRepository
+	}
 	if o.Tag {
-		candidates, err := GetTags(o.RepoPath, o.CommitLimit, SortOption(o.SortCriteria))
+		candidates, err := getTags(repository, o.CommitLimit, sortOptionOf(o))
 		if err != nil {
 			return []string{}, fmt.Errorf("retrieving commit tags failed: %w", err)
 		}
 		return candidates, nil
 	}
-	candidates, err := GetCommitHashes(o.RepoPath, o.CommitLimit)
+	var candidates []string
+	if refPatterns := RefPatterns(o); len(refPatterns) > 0 {
+		candidates, err = getCommitHashesOfRefs(repository, refPatterns, o.CommitLimit, filter)
+	} else {
+		candidates, err = getCommitHashes(repository, o.CommitLimit, filter)
+	}
 	if err != nil {
 		return []string{}, fmt.Errorf("retrieving commit hashes failed: %w", err)
 	}
 	return candidates, nil
-
 }
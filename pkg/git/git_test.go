@@ -2,13 +2,19 @@ package git
 
 import (
 	"testing"
+	"time"
 
+	"github.com/appuio/seiso/cfg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 func Test_GetCommitHashes(t *testing.T) {
 	commitLimit := 2
-	commitHashes, err := GetCommitHashes("../../", commitLimit) // Open repository from root dir
+	commitHashes, err := GetCommitHashes("../../", commitLimit, CommitFilter{}) // Open repository from root dir
 
 	assert.NoError(t, err)
 	assert.Len(t, commitHashes, commitLimit)
@@ -16,20 +22,20 @@ func Test_GetCommitHashes(t *testing.T) {
 
 func Test_GetCommitHashesAll(t *testing.T) {
 	commitLimit := -1
-	_, err := GetCommitHashes("../../", commitLimit) // Open repository from root dir
+	_, err := GetCommitHashes("../../", commitLimit, CommitFilter{}) // Open repository from root dir
 
 	assert.NoError(t, err)
 }
 
 func Test_GetCommitHashesFail(t *testing.T) {
 	commitLimit := 2
-	_, err := GetCommitHashes("not-a-repo", commitLimit)
+	_, err := GetCommitHashes("not-a-repo", commitLimit, CommitFilter{})
 
 	assert.Error(t, err)
 }
 
 func Test_GetTagsSortedInAlphabeticalOrder(t *testing.T) {
-	commitHashes, err := sortTags([]string{"v0.1.0", "2.0", "0.0.1"}, SortOptionAlphabetic)
+	commitHashes, err := sortTags([]string{"v0.1.0", "2.0", "0.0.1"}, SortOptionAlphabetic, nil)
 
 	expectInOrder := []string{"0.0.1", "2.0", "v0.1.0"}
 
@@ -38,7 +44,7 @@ func Test_GetTagsSortedInAlphabeticalOrder(t *testing.T) {
 }
 
 func Test_GetTagsSortedByVersion(t *testing.T) {
-	commitHashes, err := sortTags([]string{"0.0.5", "v0.1.0", "0.0.2", "v0.0.1"}, SortOptionVersion) // Open repository from root dir
+	commitHashes, err := sortTags([]string{"0.0.5", "v0.1.0", "0.0.2", "v0.0.1"}, SortOptionVersion, nil) // Open repository from root dir
 
 	expectInOrder := []string{"v0.1.0", "0.0.5", "0.0.2", "v0.0.1"}
 
@@ -65,7 +71,7 @@ func Test_SortByVersion(t *testing.T) {
 	unsortedTags := []string{"v3.0.1", "0.3", "v2.1.1", "0.0.1", "v5.0.2", "4.0.1-beta", "v3.0.0-alpha", "v3", "0.0.2", "v0.2.0", "3.0.0", "random"}
 	expectedSortedTags := []string{"v5.0.2", "4.0.1-beta", "v3.0.1", "v3", "3.0.0", "v3.0.0-alpha", "v2.1.1", "0.3", "v0.2.0", "0.0.2", "0.0.1"}
 
-	sortedTags, err := sortTags(unsortedTags, SortOptionVersion)
+	sortedTags, err := sortTags(unsortedTags, SortOptionVersion, nil)
 
 	assert.NoError(t, err)
 	assert.EqualValues(t, expectedSortedTags, sortedTags)
@@ -76,8 +82,109 @@ func Test_SortBInAlphabeticalOrder(t *testing.T) {
 	unsortedTags := []string{"v3.0.1", "0.3", "v2.1.1", "0.0.1", "v5.0.2", "4.0.1-beta", "v3.0.0-alpha", "v3", "0.0.2", "v0.2.0", "3.0.0", "random"}
 	expectedSortedTags := []string{"0.0.1", "0.0.2", "0.3", "3.0.0", "4.0.1-beta", "random", "v0.2.0", "v2.1.1", "v3", "v3.0.0-alpha", "v3.0.1", "v5.0.2"}
 
-	sortedTags, err := sortTags(unsortedTags, SortOptionAlphabetic)
+	sortedTags, err := sortTags(unsortedTags, SortOptionAlphabetic, nil)
 
 	assert.NoError(t, err)
 	assert.EqualValues(t, expectedSortedTags, sortedTags)
 }
+
+func Test_GetTagsSortedByDate(t *testing.T) {
+	path, master, feature, _ := newTestRepository(t)
+	repository, err := git.PlainOpen(path)
+	require.NoError(t, err)
+	tagger := func(day int) *git.CreateTagOptions {
+		return &git.CreateTagOptions{
+			Tagger:  &object.Signature{Name: "test", When: time.Date(2021, 1, day, 0, 0, 0, 0, time.UTC)},
+			Message: "release",
+		}
+	}
+	for name, target := range map[string]string{"nightly": master[1], "build-2": feature[0]} {
+		_, err := repository.CreateTag(name, plumbing.NewHash(target), nil)
+		require.NoError(t, err)
+	}
+	_, err = repository.CreateTag("v1.0.0", plumbing.NewHash(master[1]), tagger(2))
+	require.NoError(t, err)
+	_, err = repository.CreateTag("release", plumbing.NewHash(master[0]), tagger(3))
+	require.NoError(t, err)
+
+	tests := map[SortOption][]string{
+		SortOptionDate:          {"release", "v1.0.0", "build-2", "nightly"},
+		SortOptionVersionOrDate: {"v1.0.0", "release", "build-2", "nightly"},
+		SortOptionVersion:       {"v1.0.0"},
+	}
+	for sortOption, expected := range tests {
+		t.Run(string(sortOption), func(t *testing.T) {
+			tags, err := GetTags(path, 0, sortOption)
+
+			assert.NoError(t, err)
+			assert.Equal(t, expected, tags)
+		})
+	}
+}
+
+func Test_GetTags_Limit(t *testing.T) {
+	path, master, _, _ := newTestRepository(t)
+	repository, err := git.PlainOpen(path)
+	require.NoError(t, err)
+	// the tags are iterated alphabetically
+	for _, name := range []string{"v1.10.0", "v1.2.0", "v1.9.0"} {
+		_, err := repository.CreateTag(name, plumbing.NewHash(master[0]), nil)
+		require.NoError(t, err)
+	}
+	tests := map[string]struct {
+		sortTagBy SortOption
+		expected  []string
+	}{
+		"GivenVersion_ThenSortFirstTags": {
+			sortTagBy: SortOptionVersion,
+			expected:  []string{"v1.10.0", "v1.2.0"},
+		},
+		"GivenVersionOrDate_ThenSortBeforeLimit": {
+			sortTagBy: SortOptionVersionOrDate,
+			expected:  []string{"v1.10.0", "v1.9.0"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tags, err := GetTags(path, 2, tt.sortTagBy)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, tags)
+		})
+	}
+}
+
+func Test_SortByVersionOrDate(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	dates := map[string]time.Time{"v2.0.0": day(1), "v1.0.0": day(2), "latest": day(3), "b": day(1), "a": day(1)}
+
+	sortedTags, err := sortTags([]string{"a", "v1.0.0", "latest", "b", "v2.0.0"}, SortOptionVersionOrDate, dates)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v2.0.0", "v1.0.0", "latest", "a", "b"}, sortedTags)
+}
+
+func Test_IsValidSortValue(t *testing.T) {
+	for _, option := range SortOptions {
+		assert.True(t, IsValidSortValue(string(option)), option)
+	}
+	assert.False(t, IsValidSortValue("random"))
+}
+
+func Test_GetTaggedCommits(t *testing.T) {
+	path, master, feature, _ := newTestRepository(t)
+	repository, err := git.PlainOpen(path)
+	require.NoError(t, err)
+	_, err = repository.CreateTag("v1.0.0", plumbing.NewHash(master[1]), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "test", When: time.Now()},
+		Message: "release",
+	})
+	require.NoError(t, err)
+	_, err = repository.CreateTag("release/1.1", plumbing.NewHash(feature[0]), nil)
+	require.NoError(t, err)
+
+	commits, err := GetTaggedCommits(&cfg.GitConfig{RepoPath: path}, &cfg.GitAuthConfig{}, repository)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"v1.0.0": master[1], "1.1": feature[0]}, commits)
+}
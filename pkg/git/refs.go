@@ -0,0 +1,154 @@
+package git
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/appuio/seiso/cfg"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+const (
+	// LocalBranchesPattern matches all local branches
+	LocalBranchesPattern = "refs/heads/*"
+	// RemoteBranchesPattern matches all remote-tracking branches
+	RemoteBranchesPattern = "refs/remotes/*"
+)
+
+// RefPatterns returns the ref globs of the configuration. Without patterns, only the history of HEAD is read.
+func RefPatterns(o *cfg.GitConfig) []string {
+	var patterns []string
+	if o.AllBranches {
+		patterns = append(patterns, LocalBranchesPattern)
+	}
+	if o.RemoteBranches {
+		patterns = append(patterns, RemoteBranchesPattern)
+	}
+	return append(patterns, o.Refs...)
+}
+
+// GetCommitHashesOfRefs returns the commit hashes reachable from HEAD and the refs matching the glob patterns, e.g.
+// "refs/remotes/origin/release/*". A "*" also matches "/". If `commitLimit` is greater than 0, only the first commits of
+// each ref matching the filter are read. Commits reachable from several refs are returned once, ordered by committer time,
+// newest first.
+func GetCommitHashesOfRefs(repoPath string, refPatterns []string, commitLimit int, filter CommitFilter) ([]string, error) {
+	repository, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return getCommitHashesOfRefs(repository, refPatterns, commitLimit, filter)
+}
+
+func getCommitHashesOfRefs(repository *git.Repository, refPatterns []string, commitLimit int, filter CommitFilter) ([]string, error) {
+	refHashes, err := matchingRefs(repository, refPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	commitTimes := map[string]time.Time{}
+	var commitHashes []string
+	for _, refHash := range refHashes {
+		commitIter, err := logCommits(repository, refHash)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < commitLimit || commitLimit <= 0; {
+			commit, err := commitIter.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				commitIter.Close()
+				return nil, err
+			}
+			if filter.isTooOld(commit) {
+				break
+			}
+			touched, err := filter.touchesPath(commit)
+			if err != nil {
+				commitIter.Close()
+				return nil, err
+			}
+			if !touched {
+				continue
+			}
+			i++
+			hash := commit.Hash.String()
+			if _, seen := commitTimes[hash]; !seen {
+				commitTimes[hash] = commit.Committer.When
+				commitHashes = append(commitHashes, hash)
+			}
+		}
+		commitIter.Close()
+	}
+
+	sort.SliceStable(commitHashes, func(i, j int) bool {
+		return commitTimes[commitHashes[j]].Before(commitTimes[commitHashes[i]])
+	})
+	return commitHashes, nil
+}
+
+// matchingRefs returns the commit hashes of HEAD and of the refs matching the patterns. Annotated tags are resolved to
+// their commit, symbolic refs are skipped since their target is listed as well.
+func matchingRefs(repository *git.Repository, refPatterns []string) ([]plumbing.Hash, error) {
+	var hashes []plumbing.Hash
+	if head, err := repository.Head(); err == nil {
+		hashes = append(hashes, head.Hash())
+	} else if err != plumbing.ErrReferenceNotFound {
+		return nil, err
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(refPatterns))
+	for _, refPattern := range refPatterns {
+		patterns = append(patterns, globToRegexp(refPattern))
+	}
+
+	refIter, err := repository.References()
+	if err != nil {
+		return nil, err
+	}
+	defer refIter.Close()
+	err = refIter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || !matchesAny(patterns, ref.Name().String()) {
+			return nil
+		}
+		hash := ref.Hash()
+		if tag, err := repository.TagObject(hash); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				log.WithError(err).WithField("ref", ref.Name().String()).Debug("Skipped tag not pointing to a commit")
+				return nil
+			}
+			hash = commit.Hash
+		}
+		log.WithFields(log.Fields{
+			"ref":  ref.Name().String(),
+			"hash": hash.String(),
+		}).Debug("Reading history of ref")
+		hashes = append(hashes, hash)
+		return nil
+	})
+	return hashes, err
+}
+
+// globToRegexp converts a ref glob with "*" and "?" wildcards to an anchored regular expression
+func globToRegexp(glob string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(glob)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+func matchesAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
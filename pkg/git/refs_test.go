@@ -0,0 +1,135 @@
+package git
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// newTestRepository creates a repository with two commits on master, one commit on the branch "feature" and one
+// commit only reachable from the remote-tracking ref "origin/release/1.0"
+func newTestRepository(t *testing.T) (path string, master, feature, release []string) {
+	path = t.TempDir()
+	repository, err := git.PlainInit(path, false)
+	require.NoError(t, err)
+	worktree, err := repository.Worktree()
+	require.NoError(t, err)
+
+	when := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	commit := func(message string) string {
+		when = when.Add(time.Hour)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(path, "file"), []byte(message), 0644))
+		_, err := worktree.Add("file")
+		require.NoError(t, err)
+		hash, err := worktree.Commit(message, &git.CommitOptions{Author: &object.Signature{Name: "test", When: when}})
+		require.NoError(t, err)
+		return hash.String()
+	}
+
+	master = []string{commit("first")}
+	master = append([]string{commit("second")}, master...)
+	require.NoError(t, worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true}))
+	feature = []string{commit("feature")}
+	require.NoError(t, worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("release"), Create: true}))
+	release = []string{commit("release")}
+	require.NoError(t, repository.Storer.SetReference(plumbing.NewHashReference(
+		plumbing.NewRemoteReferenceName("origin", "release/1.0"), plumbing.NewHash(release[0]))))
+	require.NoError(t, repository.Storer.RemoveReference(plumbing.NewBranchReferenceName("release")))
+	require.NoError(t, worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("master")}))
+	return path, master, feature, release
+}
+
+func Test_GetCommitHashesOfRefs(t *testing.T) {
+	path, master, feature, release := newTestRepository(t)
+	tests := []struct {
+		name        string
+		patterns    []string
+		commitLimit int
+		expected    []string
+	}{
+		{
+			name:     "GivenAllBranches_ThenReturnCommitsOfLocalBranches",
+			patterns: []string{LocalBranchesPattern},
+			expected: append(feature, master...),
+		},
+		{
+			name:     "GivenRemoteBranches_ThenReturnCommitsOfHeadAndRemoteBranches",
+			patterns: []string{RemoteBranchesPattern},
+			expected: append(append(release, feature...), master...),
+		},
+		{
+			name:     "GivenRefGlob_ThenMatchNestedRefs",
+			patterns: []string{"refs/remotes/origin/release/*"},
+			expected: append(append(release, feature...), master...),
+		},
+		{
+			name:     "GivenNotMatchingGlob_ThenReturnCommitsOfHead",
+			patterns: []string{"refs/remotes/upstream/*"},
+			expected: master,
+		},
+		{
+			name:        "GivenCommitLimit_ThenLimitPerRef",
+			patterns:    []string{LocalBranchesPattern},
+			commitLimit: 1,
+			expected:    []string{feature[0], master[0]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commitHashes, err := GetCommitHashesOfRefs(path, tt.patterns, tt.commitLimit, CommitFilter{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, commitHashes)
+		})
+	}
+}
+
+func Test_GetBranchNames(t *testing.T) {
+	path, _, _, _ := newTestRepository(t)
+	repository, err := git.PlainOpen(path)
+	require.NoError(t, err)
+
+	branches, err := GetBranchNames(repository)
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"master", "feature", "release/1.0"}, branches)
+}
+
+func Test_GetBranchNames_DetachedHead(t *testing.T) {
+	path, master, _, _ := newTestRepository(t)
+	repository, err := git.PlainOpen(path)
+	require.NoError(t, err)
+	require.NoError(t, repository.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, plumbing.NewHash(master[0]))))
+	references, err := repository.References()
+	require.NoError(t, err)
+	require.NoError(t, references.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name().IsBranch() || ref.Name().IsRemote() {
+			return repository.Storer.RemoveReference(ref.Name())
+		}
+		return nil
+	}))
+
+	_, err = GetBranchNames(repository)
+
+	assert.Error(t, err)
+}
+
+func Test_BranchSlug(t *testing.T) {
+	tests := map[string]string{
+		"master":                "master",
+		"feature/Login_Form":    "feature-login-form",
+		"/fix//double--dash/":   "fix-double-dash",
+		strings.Repeat("a", 70): strings.Repeat("a", 63),
+	}
+	for branch, expected := range tests {
+		assert.Equal(t, expected, BranchSlug(branch), branch)
+	}
+}
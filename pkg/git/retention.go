@@ -0,0 +1,64 @@
+package git
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// VersionRetention protects image tags by their semantic version, in addition to keeping the most current tags overall.
+// A zero value protects no tags.
+type VersionRetention struct {
+	// PatchesPerMinor is the amount of newest versions kept of each minor version, e.g. 1.2.x. With MinorsPerMajor,
+	// only the retained minor versions are considered.
+	PatchesPerMinor int
+	// MinorsPerMajor is the amount of newest minor versions retained of each major version, e.g. 1.x. Of each
+	// retained minor version, the newest PatchesPerMinor versions are kept, but at least one.
+	MinorsPerMajor int
+	// NewestPerMajor keeps the newest version of each major version
+	NewestPerMajor bool
+}
+
+// IsEnabled returns true if any rule protects tags
+func (r VersionRetention) IsEnabled() bool {
+	return r.PatchesPerMinor > 0 || r.MinorsPerMajor > 0 || r.NewestPerMajor
+}
+
+// ProtectedTags returns the tags kept by the retention rules. Tags that are not a valid version are never protected.
+func (r VersionRetention) ProtectedTags(tags []string) []string {
+	if !r.IsEnabled() {
+		return []string{}
+	}
+	patchesPerMinor := r.PatchesPerMinor
+	if r.MinorsPerMajor > 0 && patchesPerMinor == 0 {
+		patchesPerMinor = 1
+	}
+
+	var protected []string
+	majors := map[int64]bool{}
+	minorsOfMajor := map[int64]int{}
+	patchesOfMinor := map[string]int{}
+	for _, v := range parseVersions(tags, log.DebugLevel) {
+		segments := v.Segments64()
+		major, minor := segments[0], segments[1]
+		minorKey := fmt.Sprintf("%d.%d", major, minor)
+
+		if _, seen := patchesOfMinor[minorKey]; !seen {
+			minorsOfMajor[major]++
+		}
+		minorRetained := r.MinorsPerMajor == 0 || minorsOfMajor[major] <= r.MinorsPerMajor
+		keep := minorRetained && patchesOfMinor[minorKey] < patchesPerMinor
+		patchesOfMinor[minorKey]++
+
+		if r.NewestPerMajor && !majors[major] {
+			keep = true
+		}
+		majors[major] = true
+
+		if keep {
+			log.WithField("tag", v.Original()).Debug("Protected tag by version retention")
+			protected = append(protected, v.Original())
+		}
+	}
+	return protected
+}
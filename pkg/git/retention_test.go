@@ -0,0 +1,46 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_VersionRetention_ProtectedTags(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.0.1", "v1.1.0", "v1.1.1", "v1.2.0", "v2.0.0", "v2.0.1", "v2.0.2", "v2.1.0", "v2.1.1", "latest"}
+	tests := []struct {
+		name      string
+		retention VersionRetention
+		expected  []string
+	}{
+		{
+			name:     "GivenNoRules_ThenProtectNothing",
+			expected: []string{},
+		},
+		{
+			name:      "GivenPatchesPerMinor_ThenProtectNewestPatchesOfEachMinor",
+			retention: VersionRetention{PatchesPerMinor: 1},
+			expected:  []string{"v2.1.1", "v2.0.2", "v1.2.0", "v1.1.1", "v1.0.1"},
+		},
+		{
+			name:      "GivenMinorsPerMajor_ThenProtectNewestPatchOfNewestMinors",
+			retention: VersionRetention{MinorsPerMajor: 2},
+			expected:  []string{"v2.1.1", "v2.0.2", "v1.2.0", "v1.1.1"},
+		},
+		{
+			name:      "GivenMinorsAndPatches_ThenProtectPatchesOfRetainedMinors",
+			retention: VersionRetention{MinorsPerMajor: 1, PatchesPerMinor: 2},
+			expected:  []string{"v2.1.1", "v2.1.0", "v1.2.0"},
+		},
+		{
+			name:      "GivenNewestPerMajor_ThenProtectNewestOfEachMajor",
+			retention: VersionRetention{NewestPerMajor: true},
+			expected:  []string{"v2.1.1", "v1.2.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.retention.ProtectedTags(tags))
+		})
+	}
+}
@@ -3,6 +3,7 @@ package git
 import (
 	"errors"
 	"sort"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	log "github.com/sirupsen/logrus"
@@ -16,42 +17,92 @@ const (
 	SortOptionVersion SortOption = "version"
 	// SortOptionAlphabetic sorts in alphabetical order
 	SortOptionAlphabetic SortOption = "alphabetic"
+	// SortOptionDate sorts by tagger date (annotated tags) or committer date (lightweight tags), newest first
+	SortOptionDate SortOption = "date"
+	// SortOptionVersionOrDate sorts by version, followed by the tags that are not a valid version sorted by date
+	SortOptionVersionOrDate SortOption = "version-or-date"
 )
 
+// DefaultSortOption sorts the git tags of repositories and forges if no sort option is given. The tags of a candidates
+// file keep the order of the file instead.
+const DefaultSortOption = SortOptionVersion
+
+// SortOptions are all valid sort options
+var SortOptions = []SortOption{SortOptionVersion, SortOptionAlphabetic, SortOptionDate, SortOptionVersionOrDate}
+
 // IsValidSortValue function tries to cast the string to SortedTagBy type
 func IsValidSortValue(sortValue string) bool {
-	return SortOption(sortValue) == SortOptionVersion || SortOption(sortValue) == SortOptionAlphabetic
+	for _, option := range SortOptions {
+		if SortOption(sortValue) == option {
+			return true
+		}
+	}
+	return false
 }
 
-// Sort function sorts the slice according to the sort type
-func sortTags(tags []string, sortTagBy SortOption) ([]string, error) {
+// Sort function sorts the slice according to the sort type. The dates are only needed to sort by date.
+func sortTags(tags []string, sortTagBy SortOption, dates map[string]time.Time) ([]string, error) {
 	switch sortTagBy {
 
 	case SortOptionVersion:
-		var versionTags []*version.Version
-		for _, raw := range tags {
-			version, err := version.NewVersion(raw)
-			if err != nil {
-				log.WithError(err).WithField("tag", raw).Warn("Skipped invalid version")
-			} else {
-				versionTags = append(versionTags, version)
-			}
-		}
-
-		sort.Sort(sort.Reverse(version.Collection(versionTags)))
-
-		sortedTags := make([]string, len(versionTags))
-		for i, sortedVersion := range versionTags {
-			sortedTags[i] = sortedVersion.Original()
-		}
-
-		return sortedTags, nil
+		return versionStrings(parseVersions(tags, log.WarnLevel)), nil
 
 	case SortOptionAlphabetic:
 		sort.Strings(tags)
 		return tags, nil
 
+	case SortOptionDate:
+		return sortTagsByDate(tags, dates), nil
+
+	case SortOptionVersionOrDate:
+		versionTags := versionStrings(parseVersions(tags, log.DebugLevel))
+		var otherTags []string
+		for _, tag := range tags {
+			if _, err := version.NewVersion(tag); err != nil {
+				otherTags = append(otherTags, tag)
+			}
+		}
+		return append(versionTags, sortTagsByDate(otherTags, dates)...), nil
+
 	default:
 		return nil, errors.New("Undefined sort type")
 	}
 }
+
+// sortTagsByDate sorts the tags by their date, newest first. Tags with the same date are sorted alphabetically.
+func sortTagsByDate(tags []string, dates map[string]time.Time) []string {
+	sorted := make([]string, len(tags))
+	copy(sorted, tags)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		first, second := dates[sorted[i]], dates[sorted[j]]
+		if first.Equal(second) {
+			return sorted[i] < sorted[j]
+		}
+		return first.After(second)
+	})
+	return sorted
+}
+
+func versionStrings(versions []*version.Version) []string {
+	sortedTags := make([]string, len(versions))
+	for i, sortedVersion := range versions {
+		sortedTags[i] = sortedVersion.Original()
+	}
+	return sortedTags
+}
+
+// parseVersions parses the tags as versions (with optional "v" prefix) and returns them sorted, newest first. Tags that
+// are not a valid version are logged with the given level and skipped.
+func parseVersions(tags []string, skipLevel log.Level) []*version.Version {
+	var versions []*version.Version
+	for _, raw := range tags {
+		parsed, err := version.NewVersion(raw)
+		if err != nil {
+			log.WithError(err).WithField("tag", raw).Log(skipLevel, "Skipped invalid version")
+			continue
+		}
+		versions = append(versions, parsed)
+	}
+	sort.Sort(sort.Reverse(version.Collection(versions)))
+	return versions
+}
@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	core "k8s.io/client-go/kubernetes/typed/core/v1"
 )
@@ -24,3 +25,13 @@ func NewCoreV1Client() (*core.CoreV1Client, error) {
 
 	return core.NewForConfig(restConfig)
 }
+
+// NewDiscoveryClient creates a new discovery client
+func NewDiscoveryClient() (discovery.DiscoveryInterface, error) {
+	restConfig, err := RestConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return discovery.NewDiscoveryClientForConfig(restConfig)
+}
@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+type (
+	// ResourceResolver resolves resources to the versions served by the cluster, using the discovery API
+	ResourceResolver struct {
+		client    discovery.DiscoveryInterface
+		mutex     sync.Mutex
+		groups    map[string]metav1.APIGroup
+		resources map[string][]string
+	}
+)
+
+var versionPattern = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+// NewResourceResolver creates a new ResourceResolver. The discovery results are cached for the lifetime of the resolver.
+func NewResourceResolver(client discovery.DiscoveryInterface) *ResourceResolver {
+	return &ResourceResolver{
+		client:    client,
+		resources: map[string][]string{},
+	}
+}
+
+// ParseResource parses a resource in the format "resource.version.group", "resource.group" or "resource" (core group),
+// e.g. "rollouts.v1alpha1.argoproj.io". Without version, the preferred version of the group is used.
+func ParseResource(value string) (schema.GroupVersionResource, error) {
+	if value == "" || strings.Contains(value, "/") {
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid resource %q, expected \"resource.version.group\"", value)
+	}
+	fullySpecified, groupResource := schema.ParseResourceArg(value)
+	if fullySpecified != nil && versionPattern.MatchString(fullySpecified.Version) {
+		return *fullySpecified, nil
+	}
+	return groupResource.WithVersion(""), nil
+}
+
+// Resolve returns the resource in a version served by the cluster. The requested version is used if it is served,
+// otherwise the preferred version of the group or any other version serving the resource. The second value is false
+// if the group or the resource is not served.
+func (r *ResourceResolver) Resolve(resource schema.GroupVersionResource) (schema.GroupVersionResource, bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.groups == nil {
+		groupList, err := r.client.ServerGroups()
+		if err != nil {
+			return resource, false, fmt.Errorf("could not discover API groups: %w", err)
+		}
+		r.groups = make(map[string]metav1.APIGroup, len(groupList.Groups))
+		for _, group := range groupList.Groups {
+			r.groups[group.Name] = group
+		}
+	}
+	group, found := r.groups[resource.Group]
+	if !found {
+		log.WithField("group", resource.Group).Debugf("Skipping %s, the API group is not served", resource.String())
+		return resource, false, nil
+	}
+	for _, version := range r.candidateVersions(group, resource.Version) {
+		resources, err := r.servedResources(schema.GroupVersion{Group: resource.Group, Version: version})
+		if err != nil {
+			return resource, false, err
+		}
+		if funk.ContainsString(resources, resource.Resource) {
+			return resource.GroupResource().WithVersion(version), true, nil
+		}
+	}
+	log.WithField("group", resource.Group).Debugf("Skipping %s, the resource is not served", resource.String())
+	return resource, false, nil
+}
+
+// candidateVersions returns the requested version, followed by the preferred version and the other versions of the group
+func (r *ResourceResolver) candidateVersions(group metav1.APIGroup, requested string) []string {
+	var versions []string
+	for _, version := range group.Versions {
+		if version.Version == requested {
+			versions = append(versions, requested)
+		}
+	}
+	versions = append(versions, group.PreferredVersion.Version)
+	for _, version := range group.Versions {
+		versions = append(versions, version.Version)
+	}
+	return funk.UniqString(versions)
+}
+
+// servedResources returns the names of the resources served in the group version, without subresources
+func (r *ResourceResolver) servedResources(groupVersion schema.GroupVersion) ([]string, error) {
+	if resources, found := r.resources[groupVersion.String()]; found {
+		return resources, nil
+	}
+	resourceList, err := r.client.ServerResourcesForGroupVersion(groupVersion.String())
+	if err != nil {
+		return nil, fmt.Errorf("could not discover resources of %s: %w", groupVersion.String(), err)
+	}
+	resources := []string{}
+	for _, resource := range resourceList.APIResources {
+		if !strings.Contains(resource.Name, "/") {
+			resources = append(resources, resource.Name)
+		}
+	}
+	r.resources[groupVersion.String()] = resources
+	return resources, nil
+}
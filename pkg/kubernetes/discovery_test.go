@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newTestResolver() *ResourceResolver {
+	client := &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods"}, {Name: "pods/log"}}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments"}, {Name: "daemonsets"}}},
+		{GroupVersion: "batch/v1", APIResources: []metav1.APIResource{{Name: "jobs"}}},
+		{GroupVersion: "batch/v1beta1", APIResources: []metav1.APIResource{{Name: "cronjobs"}}},
+		{GroupVersion: "argoproj.io/v1alpha1", APIResources: []metav1.APIResource{{Name: "rollouts"}}},
+	}}}
+	return NewResourceResolver(client)
+}
+
+func Test_ResourceResolver_Resolve(t *testing.T) {
+	tests := map[string]struct {
+		resource  schema.GroupVersionResource
+		expected  schema.GroupVersionResource
+		notServed bool
+	}{
+		"ShouldUseRequestedVersion": {
+			resource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			expected: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		},
+		"ShouldUseOtherServedVersion": {
+			resource: schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"},
+			expected: schema.GroupVersionResource{Group: "batch", Version: "v1beta1", Resource: "cronjobs"},
+		},
+		"ShouldUsePreferredVersion_IfVersionIsEmpty": {
+			resource: schema.GroupVersionResource{Group: "argoproj.io", Resource: "rollouts"},
+			expected: schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"},
+		},
+		"ShouldResolveCoreGroup": {
+			resource: schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+			expected: schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+		},
+		"ShouldSkipAbsentGroup": {
+			resource:  schema.GroupVersionResource{Group: "extensions", Version: "v1beta1", Resource: "deployments"},
+			notServed: true,
+		},
+		"ShouldSkipAbsentResource": {
+			resource:  schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"},
+			notServed: true,
+		},
+	}
+	resolver := newTestResolver()
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resolved, found, err := resolver.Resolve(tt.resource)
+			assert.NoError(t, err)
+			assert.Equal(t, !tt.notServed, found)
+			if found {
+				assert.Equal(t, tt.expected, resolved)
+			}
+		})
+	}
+}
+
+func Test_ParseResource(t *testing.T) {
+	tests := map[string]struct {
+		expected schema.GroupVersionResource
+		wantErr  bool
+	}{
+		"rollouts.v1alpha1.argoproj.io": {expected: schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}},
+		"backups.velero.io":             {expected: schema.GroupVersionResource{Group: "velero.io", Resource: "backups"}},
+		"deployments.apps":              {expected: schema.GroupVersionResource{Group: "apps", Resource: "deployments"}},
+		"pods":                          {expected: schema.GroupVersionResource{Resource: "pods"}},
+		"apps/v1/deployments":           {wantErr: true},
+		"":                              {wantErr: true},
+	}
+	for value, tt := range tests {
+		t.Run(value, func(t *testing.T) {
+			resource, err := ParseResource(value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, resource)
+		})
+	}
+}
+
+func Test_ListResources_SkipsResourcesNotServed(t *testing.T) {
+	client := dynfake.NewSimpleDynamicClient(scheme.Scheme, newTestPod("app-1", "namespace/app:a1"))
+	helper := &kubernetesImpl{client: client, resolver: newTestResolver(), snapshot: NewSnapshot()}
+
+	objects, err := helper.ListResources(context.Background(), "namespace", schema.GroupVersionResource{Group: "extensions", Version: "v1beta1", Resource: "deployments"})
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+	assert.Empty(t, client.Actions())
+
+	objects, err = helper.ListResources(context.Background(), "namespace", podResource)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+}
@@ -0,0 +1,159 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type (
+	// Snapshot holds the objects of each resource and namespace, listed once and shared by all usage checks. A snapshot
+	// can be saved to a file and loaded by later commands, as long as it is not older than a maximum age.
+	Snapshot struct {
+		mutex   sync.Mutex
+		created time.Time
+		lists   map[string]*resourceList
+	}
+	// resourceList contains the listed objects and an index of all their string values
+	resourceList struct {
+		objects []unstructured.Unstructured
+		values  []string
+	}
+	// snapshotFile is the serialized form of a Snapshot
+	snapshotFile struct {
+		Created time.Time                           `json:"created"`
+		Lists   map[string][]map[string]interface{} `json:"lists"`
+	}
+)
+
+// NewSnapshot creates an empty snapshot
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		created: time.Now(),
+		lists:   map[string]*resourceList{},
+	}
+}
+
+// LoadSnapshot reads a snapshot saved by Save. A new empty snapshot is returned if the file does not exist or the saved
+// snapshot is older than maxAge.
+func LoadSnapshot(path string, maxAge time.Duration) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewSnapshot(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := snapshotFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("could not parse snapshot '%s': %w", path, err)
+	}
+	if time.Since(file.Created) > maxAge {
+		return NewSnapshot(), nil
+	}
+	snapshot := &Snapshot{
+		created: file.Created,
+		lists:   make(map[string]*resourceList, len(file.Lists)),
+	}
+	for key, items := range file.Lists {
+		objects := make([]unstructured.Unstructured, 0, len(items))
+		for _, item := range items {
+			objects = append(objects, unstructured.Unstructured{Object: item})
+		}
+		snapshot.lists[key] = newResourceList(objects)
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot to the file. The file is only readable by the owner, as the listed objects can contain
+// sensitive values like environment variables.
+func (s *Snapshot) Save(path string) error {
+	s.mutex.Lock()
+	file := snapshotFile{
+		Created: s.created,
+		Lists:   make(map[string][]map[string]interface{}, len(s.lists)),
+	}
+	for key, list := range s.lists {
+		items := make([]map[string]interface{}, 0, len(list.objects))
+		for _, object := range list.objects {
+			items = append(items, object.Object)
+		}
+		file.Lists[key] = items
+	}
+	s.mutex.Unlock()
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Created returns the time the snapshot was created
+func (s *Snapshot) Created() time.Time {
+	return s.created
+}
+
+func (s *Snapshot) get(namespace string, resource schema.GroupVersionResource) (*resourceList, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	list, found := s.lists[snapshotKey(namespace, resource)]
+	return list, found
+}
+
+func (s *Snapshot) set(namespace string, resource schema.GroupVersionResource, objects []unstructured.Unstructured) *resourceList {
+	list := newResourceList(objects)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lists[snapshotKey(namespace, resource)] = list
+	return list
+}
+
+func snapshotKey(namespace string, resource schema.GroupVersionResource) string {
+	return namespace + "/" + resource.String()
+}
+
+func newResourceList(objects []unstructured.Unstructured) *resourceList {
+	unique := map[string]bool{}
+	for _, object := range objects {
+		collectValues(object.Object, unique)
+	}
+	values := make([]string, 0, len(unique))
+	for value := range unique {
+		values = append(values, value)
+	}
+	return &resourceList{objects: objects, values: values}
+}
+
+// collectValues adds all string values of the object to the set
+func collectValues(genericObject interface{}, values map[string]bool) {
+	switch object := genericObject.(type) {
+	case map[string]interface{}:
+		for _, value := range object {
+			collectValues(value, values)
+		}
+	case []interface{}:
+		for _, value := range object {
+			collectValues(value, values)
+		}
+	case string:
+		values[object] = true
+	}
+}
+
+// contains evaluates if any string value of the listed objects contains the given string
+func (l *resourceList) contains(value string) bool {
+	for _, candidate := range l.values {
+		if strings.Contains(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
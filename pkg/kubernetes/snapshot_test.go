@@ -0,0 +1,71 @@
+package kubernetes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+var podResource = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+func newTestPod(name, image string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "namespace"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+	}
+}
+
+func Test_ResourceContains_ListsOnce(t *testing.T) {
+	client := dynfake.NewSimpleDynamicClient(scheme.Scheme, newTestPod("app-1", "namespace/app:a1"), newTestPod("app-2", "namespace/app:a2"))
+	helper := NewFromClient(client)
+	ctx := context.Background()
+
+	for value, expected := range map[string]bool{"app:a1": true, "app:a2": true, "app:a3": false} {
+		contains, err := helper.ResourceContains(ctx, "namespace", value, podResource)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, contains, value)
+	}
+	objects, err := helper.ListResources(ctx, "namespace", podResource)
+	assert.NoError(t, err)
+	assert.Len(t, objects, 2)
+
+	assert.Len(t, client.Actions(), 1)
+}
+
+func Test_Snapshot_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	snapshot := NewSnapshot()
+	client := dynfake.NewSimpleDynamicClient(scheme.Scheme, newTestPod("app-1", "namespace/app:a1"))
+	_, err := (&kubernetesImpl{client: client, snapshot: snapshot}).ListResources(context.Background(), "namespace", podResource)
+	require.NoError(t, err)
+
+	require.NoError(t, snapshot.Save(path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := LoadSnapshot(path, time.Hour)
+	require.NoError(t, err)
+	contains, err := NewWithSnapshot(loaded).ResourceContains(context.Background(), "namespace", "app:a1", podResource)
+	assert.NoError(t, err)
+	assert.True(t, contains)
+
+	expired, err := LoadSnapshot(path, 0)
+	require.NoError(t, err)
+	_, found := expired.get("namespace", podResource)
+	assert.False(t, found)
+
+	missing, err := LoadSnapshot(filepath.Join(t.TempDir(), "missing.json"), time.Hour)
+	require.NoError(t, err)
+	assert.NotNil(t, missing)
+}
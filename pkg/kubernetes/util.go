@@ -15,30 +15,90 @@ type (
 	// Kubernetes defines the interface to interact with K8s
 	Kubernetes interface {
 		ResourceContains(ctx context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error)
+		ListResources(ctx context.Context, namespace string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error)
 	}
 	// kubernetesImpl is an implementation of the interface. (Better name? introduced for better testing support)
 	kubernetesImpl struct {
-		client dynamic.Interface
+		client   dynamic.Interface
+		resolver *ResourceResolver
+		snapshot *Snapshot
 	}
 )
 
-// New creates a new Kubernetes instance
+// listPageSize is the amount of objects requested per page when listing resources
+const listPageSize = 500
+
+// New creates a new Kubernetes instance. Each resource is listed once per namespace and kept for the lifetime of the
+// instance.
 func New() Kubernetes {
-	return &kubernetesImpl{}
+	return NewWithSnapshot(NewSnapshot())
+}
+
+// NewWithSnapshot creates a new Kubernetes instance that reads resources from the snapshot, and lists resources missing
+// in the snapshot into it. The snapshot can be shared by several instances. Resources are listed in the version served
+// by the cluster, resources that are not served are empty.
+func NewWithSnapshot(snapshot *Snapshot) Kubernetes {
+	return &kubernetesImpl{snapshot: snapshot}
+}
+
+// NewFromClient creates a new Kubernetes instance using the given dynamic client, e.g. a fake client in tests
+func NewFromClient(client dynamic.Interface) Kubernetes {
+	return &kubernetesImpl{client: client, snapshot: NewSnapshot()}
 }
 
 // ResourceContains evaluates if a given resource contains a given string
 func (k *kubernetesImpl) ResourceContains(ctx context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error) {
-	err := k.initClient()
+	list, err := k.list(ctx, namespace, resource)
 	if err != nil {
 		return false, err
 	}
-	objectlist, err := k.client.Resource(resource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	return list.contains(value), nil
+}
+
+// ListResources lists all objects of the given resource in the namespace
+func (k *kubernetesImpl) ListResources(ctx context.Context, namespace string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	list, err := k.list(ctx, namespace, resource)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
+	return list.objects, nil
+}
 
-	return UnstructuredListContains(objectlist, value), nil
+// list returns the objects of the resource from the snapshot, or lists them page by page if they are not in the
+// snapshot yet
+func (k *kubernetesImpl) list(ctx context.Context, namespace string, resource schema.GroupVersionResource) (*resourceList, error) {
+	if list, found := k.snapshot.get(namespace, resource); found {
+		return list, nil
+	}
+	err := k.initClient()
+	if err != nil {
+		return nil, err
+	}
+	served := resource
+	if k.resolver != nil {
+		var found bool
+		served, found, err = k.resolver.Resolve(resource)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return k.snapshot.set(namespace, resource, nil), nil
+		}
+	}
+	var objects []unstructured.Unstructured
+	listOptions := metav1.ListOptions{Limit: listPageSize}
+	for {
+		objectlist, err := k.client.Resource(served).Namespace(namespace).List(ctx, listOptions)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, objectlist.Items...)
+		listOptions.Continue = objectlist.GetContinue()
+		if listOptions.Continue == "" {
+			break
+		}
+	}
+	return k.snapshot.set(namespace, resource, objects), nil
 }
 
 func (k *kubernetesImpl) initClient() error {
@@ -47,7 +107,12 @@ func (k *kubernetesImpl) initClient() error {
 		if err != nil {
 			return err
 		}
+		discoveryClient, err := NewDiscoveryClient()
+		if err != nil {
+			return err
+		}
 		k.client = client
+		k.resolver = NewResourceResolver(discoveryClient)
 	}
 	return nil
 }
@@ -2,19 +2,22 @@ package namespace
 
 import (
 	"context"
+	"fmt"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/dynamic"
+	"github.com/appuio/seiso/pkg/kubernetes"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 const resourceCheckerName = "Resources"
 
 type ResourceChecker struct {
-	dynamicClient dynamic.Interface
+	helper    kubernetes.Kubernetes
+	resources []schema.GroupVersionResource
 }
 
-func NewResourceChecker(dynamicClient dynamic.Interface) *ResourceChecker {
-	return &ResourceChecker{dynamicClient: dynamicClient}
+// NewResourceChecker creates a checker that considers namespaces with any of the given resources as not empty
+func NewResourceChecker(helper kubernetes.Kubernetes, resources []schema.GroupVersionResource) *ResourceChecker {
+	return &ResourceChecker{helper: helper, resources: resources}
 }
 
 func (rc ResourceChecker) Name() string {
@@ -22,13 +25,14 @@ func (rc ResourceChecker) Name() string {
 }
 
 func (rc ResourceChecker) NonEmptyNamespaces(ctx context.Context, namespaceMap map[string]struct{}) error {
-	for _, r := range resources {
-		resourceList, err := rc.dynamicClient.Resource(r).List(ctx, metav1.ListOptions{})
+	for _, r := range rc.resources {
+		// an empty namespace lists the resources of all namespaces
+		resourceList, err := rc.helper.ListResources(ctx, "", r)
 		if err != nil {
-			return nil
+			return fmt.Errorf("could not list %s: %w", r.String(), err)
 		}
 
-		for _, resource := range resourceList.Items {
+		for _, resource := range resourceList {
 			if resource.GetDeletionTimestamp().IsZero() {
 				// Found active resource in namespace
 				namespaceMap[resource.GetNamespace()] = struct{}{}
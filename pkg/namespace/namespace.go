@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/appuio/seiso/pkg/kubernetes"
 	"github.com/appuio/seiso/pkg/util"
 	"github.com/karrick/tparse/v2"
 	log "github.com/sirupsen/logrus"
@@ -12,20 +13,18 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
 	core "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
 const cleanAnnotation = "syn.tools/clean"
 
 var (
+	// resources make a namespace non-empty. The version is resolved to the version served by the cluster.
 	resources = []schema.GroupVersionResource{
 		{Version: "v1", Resource: "pods"},
 		{Group: "apps", Version: "v1", Resource: "statefulsets"},
 		{Group: "apps", Version: "v1", Resource: "deployments"},
 		{Group: "apps", Version: "v1", Resource: "daemonsets"},
-		{Group: "extensions", Version: "v1beta1", Resource: "daemonsets"},
-		{Group: "extensions", Version: "v1beta1", Resource: "deployments"},
 	}
 )
 
@@ -33,11 +32,12 @@ type (
 	NamespacesService struct {
 		configuration ServiceConfiguration
 		client        core.NamespaceInterface
-		dynamicClient dynamic.Interface
 		checkers      []Checker
 	}
 	ServiceConfiguration struct {
 		Batch bool
+		// Resources make a namespace non-empty in addition to the predefined resources, e.g. custom resources
+		Resources []schema.GroupVersionResource
 	}
 	Checker interface {
 		NonEmptyNamespaces(context.Context, map[string]struct{}) error
@@ -46,11 +46,12 @@ type (
 )
 
 // NewNamespacesService creates a new Service instance
-func NewNamespacesService(client core.NamespaceInterface, dynamicClient dynamic.Interface, configuration ServiceConfiguration) NamespacesService {
+func NewNamespacesService(client core.NamespaceInterface, helper kubernetes.Kubernetes, configuration ServiceConfiguration) NamespacesService {
+	checkedResources := append(append([]schema.GroupVersionResource{}, resources...), configuration.Resources...)
 	return NamespacesService{
 		client:        client,
 		configuration: configuration,
-		checkers:      []Checker{NewHelmChecker(), NewResourceChecker(dynamicClient)},
+		checkers:      []Checker{NewHelmChecker(), NewResourceChecker(helper, checkedResources)},
 	}
 }
 
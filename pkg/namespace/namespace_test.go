@@ -2,9 +2,11 @@ package namespace
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/appuio/seiso/pkg/kubernetes"
 	"github.com/appuio/seiso/pkg/util"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -15,6 +17,7 @@ import (
 	dynFake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func Test_GetEmptyFor(t *testing.T) {
@@ -104,7 +107,7 @@ func Test_GetEmptyFor(t *testing.T) {
 			fakeClient := clientset.CoreV1().Namespaces()
 			fakeDynamicClient := dynFake.NewSimpleDynamicClient(scheme.Scheme, tt.objs...)
 
-			service := NewNamespacesService(fakeClient, fakeDynamicClient, ServiceConfiguration{})
+			service := NewNamespacesService(fakeClient, kubernetes.NewFromClient(fakeDynamicClient), ServiceConfiguration{})
 
 			// By default, the HelmChecker is included in the list of checkers.
 			// But it requires an active k8s cluster to work.
@@ -152,3 +155,15 @@ func annotatedNamespace(name, cleanAnnotationValue string) *corev1.Namespace {
 	}
 
 }
+
+func Test_ResourceChecker_ReturnsListError(t *testing.T) {
+	fakeDynamicClient := dynFake.NewSimpleDynamicClient(scheme.Scheme)
+	fakeDynamicClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	checker := NewResourceChecker(kubernetes.NewFromClient(fakeDynamicClient), resources)
+
+	err := checker.NonEmptyNamespaces(context.Background(), map[string]struct{}{})
+
+	assert.Error(t, err)
+}
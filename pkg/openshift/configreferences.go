@@ -0,0 +1,221 @@
+package openshift
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/appuio/seiso/pkg/kubernetes"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type (
+	// ConfigReference is a reference to a ConfigMap or Secret found in a field of a Kubernetes object
+	ConfigReference struct {
+		// Kind is either ConfigMap or Secret
+		Kind string
+		// Name is the name of the referenced ConfigMap or Secret
+		Name string
+		// Object is the kind and name of the referencing object, e.g. "Deployment/app"
+		Object string
+		// Field is the path of the field containing the reference, e.g. "spec.template.spec.volumes[config].configMap.name"
+		Field string
+	}
+	// configReferenceFinder collects the references of an object
+	configReferenceFinder struct {
+		object     string
+		references []ConfigReference
+	}
+	// configReferenceField describes a field with a reference relative to an element or object
+	configReferenceField struct {
+		kind string
+		path []string
+	}
+)
+
+const (
+	// ConfigMapKind is the kind of references to ConfigMaps
+	ConfigMapKind = "ConfigMap"
+	// SecretKind is the kind of references to Secrets
+	SecretKind = "Secret"
+)
+
+var (
+	// ConfigReferenceResources are the resources searched for ConfigMap and Secret references in the structured
+	// reference mode
+	ConfigReferenceResources = append(append([]schema.GroupVersionResource{}, PredefinedResources...),
+		schema.GroupVersionResource{Version: "v1", Resource: "serviceaccounts"},
+		schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+		schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"},
+		schema.GroupVersionResource{Group: "build.openshift.io", Version: "v1", Resource: "buildconfigs"},
+	)
+	volumeReferenceFields = []configReferenceField{
+		{kind: ConfigMapKind, path: []string{"configMap", "name"}},
+		{kind: SecretKind, path: []string{"secret", "secretName"}},
+		{kind: SecretKind, path: []string{"csi", "nodePublishSecretRef", "name"}},
+		{kind: SecretKind, path: []string{"cephfs", "secretRef", "name"}},
+		{kind: SecretKind, path: []string{"rbd", "secretRef", "name"}},
+		{kind: SecretKind, path: []string{"iscsi", "secretRef", "name"}},
+		{kind: SecretKind, path: []string{"flexVolume", "secretRef", "name"}},
+		{kind: SecretKind, path: []string{"scaleIO", "secretRef", "name"}},
+		{kind: SecretKind, path: []string{"storageos", "secretRef", "name"}},
+		{kind: SecretKind, path: []string{"azureFile", "secretName"}},
+	}
+	projectionReferenceFields = []configReferenceField{
+		{kind: ConfigMapKind, path: []string{"configMap", "name"}},
+		{kind: SecretKind, path: []string{"secret", "name"}},
+	}
+	envFromReferenceFields = []configReferenceField{
+		{kind: ConfigMapKind, path: []string{"configMapRef", "name"}},
+		{kind: SecretKind, path: []string{"secretRef", "name"}},
+	}
+	envReferenceFields = []configReferenceField{
+		{kind: ConfigMapKind, path: []string{"valueFrom", "configMapKeyRef", "name"}},
+		{kind: SecretKind, path: []string{"valueFrom", "secretKeyRef", "name"}},
+	}
+	nameReferenceFields = []configReferenceField{
+		{kind: SecretKind, path: []string{"name"}},
+	}
+	buildConfigReferenceFields = []configReferenceField{
+		{kind: SecretKind, path: []string{"spec", "source", "sourceSecret", "name"}},
+		{kind: SecretKind, path: []string{"spec", "output", "pushSecret", "name"}},
+		{kind: SecretKind, path: []string{"spec", "strategy", "sourceStrategy", "pullSecret", "name"}},
+		{kind: SecretKind, path: []string{"spec", "strategy", "dockerStrategy", "pullSecret", "name"}},
+		{kind: SecretKind, path: []string{"spec", "strategy", "customStrategy", "pullSecret", "name"}},
+	}
+	routeReferenceFields = []configReferenceField{
+		{kind: SecretKind, path: []string{"spec", "tls", "externalCertificate", "name"}},
+	}
+)
+
+// FindConfigReferences returns the references to ConfigMaps and Secrets of the object: volumes and projected volumes
+// (including the secrets of CSI, CephFS, RBD, iSCSI, FlexVolume, ScaleIO, StorageOS and Azure File volumes), envFrom
+// and env valueFrom of all containers, and image pull secrets of pod specs (also in templates), secrets and
+// image pull secrets of ServiceAccounts, TLS secrets of Ingresses and Routes, and source, push, pull and build secrets
+// and ConfigMaps of BuildConfigs.
+func FindConfigReferences(object unstructured.Unstructured) []ConfigReference {
+	finder := configReferenceFinder{object: object.GetKind() + "/" + object.GetName()}
+
+	for _, podSpecPath := range podSpecPaths {
+		podSpec, found, _ := unstructured.NestedMap(object.Object, podSpecPath...)
+		if !found {
+			continue
+		}
+		prefix := strings.Join(podSpecPath, ".")
+		finder.addList(podSpec, prefix, []string{"volumes"}, func(volume map[string]interface{}, field string) {
+			finder.addFields(volume, field, volumeReferenceFields)
+			finder.addList(volume, field, []string{"projected", "sources"}, func(source map[string]interface{}, field string) {
+				finder.addFields(source, field, projectionReferenceFields)
+			})
+		})
+		for _, containerField := range []string{"containers", "initContainers", "ephemeralContainers"} {
+			finder.addList(podSpec, prefix, []string{containerField}, func(container map[string]interface{}, field string) {
+				finder.addList(container, field, []string{"envFrom"}, func(envFrom map[string]interface{}, field string) {
+					finder.addFields(envFrom, field, envFromReferenceFields)
+				})
+				finder.addList(container, field, []string{"env"}, func(env map[string]interface{}, field string) {
+					finder.addFields(env, field, envReferenceFields)
+				})
+			})
+		}
+		finder.addList(podSpec, prefix, []string{"imagePullSecrets"}, func(secret map[string]interface{}, field string) {
+			finder.addFields(secret, field, nameReferenceFields)
+		})
+	}
+
+	switch object.GetKind() {
+	case "ServiceAccount":
+		for _, listField := range []string{"secrets", "imagePullSecrets"} {
+			finder.addList(object.Object, "", []string{listField}, func(secret map[string]interface{}, field string) {
+				finder.addFields(secret, field, nameReferenceFields)
+			})
+		}
+	case "Ingress":
+		finder.addList(object.Object, "", []string{"spec", "tls"}, func(tls map[string]interface{}, field string) {
+			finder.addFields(tls, field, []configReferenceField{{kind: SecretKind, path: []string{"secretName"}}})
+		})
+	case "Route":
+		finder.addFields(object.Object, "", routeReferenceFields)
+	case "BuildConfig":
+		finder.addFields(object.Object, "", buildConfigReferenceFields)
+		finder.addList(object.Object, "", []string{"spec", "source", "secrets"}, func(secret map[string]interface{}, field string) {
+			finder.addFields(secret, field, []configReferenceField{{kind: SecretKind, path: []string{"secret", "name"}}})
+		})
+		finder.addList(object.Object, "", []string{"spec", "source", "configMaps"}, func(configMap map[string]interface{}, field string) {
+			finder.addFields(configMap, field, []configReferenceField{{kind: ConfigMapKind, path: []string{"configMap", "name"}}})
+		})
+		finder.addList(object.Object, "", []string{"spec", "strategy", "customStrategy", "secrets"}, func(secret map[string]interface{}, field string) {
+			finder.addFields(secret, field, []configReferenceField{{kind: SecretKind, path: []string{"secretSource", "name"}}})
+		})
+		for _, strategy := range []string{"sourceStrategy", "dockerStrategy", "customStrategy"} {
+			finder.addList(object.Object, "", []string{"spec", "strategy", strategy, "env"}, func(env map[string]interface{}, field string) {
+				finder.addFields(env, field, envReferenceFields)
+			})
+		}
+	}
+	return finder.references
+}
+
+// addFields adds the references found in the fields of the element
+func (f *configReferenceFinder) addFields(element map[string]interface{}, prefix string, fields []configReferenceField) {
+	for _, field := range fields {
+		name, _, _ := unstructured.NestedString(element, field.path...)
+		if name == "" {
+			continue
+		}
+		f.references = append(f.references, ConfigReference{
+			Kind:   field.kind,
+			Name:   name,
+			Object: f.object,
+			Field:  appendPath(prefix, strings.Join(field.path, ".")),
+		})
+	}
+}
+
+// addList calls the function for each element of the list, with the path of the element
+func (f *configReferenceFinder) addList(element map[string]interface{}, prefix string, path []string, fn func(map[string]interface{}, string)) {
+	items, _, _ := unstructured.NestedSlice(element, path...)
+	for i, item := range items {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fn(itemMap, appendPath(prefix, strings.Join(path, ".")+"["+elementName(itemMap, i)+"]"))
+	}
+}
+
+func appendPath(prefix, field string) string {
+	if prefix == "" {
+		return field
+	}
+	return prefix + "." + field
+}
+
+// GetConfigReferences retrieves the references to ConfigMaps or Secrets (depending on the kind) of the resources in the
+// namespace, grouped by the name of the referenced ConfigMap or Secret
+func GetConfigReferences(ctx context.Context, helper kubernetes.Kubernetes, resources []schema.GroupVersionResource, namespace, kind string) (map[string][]ConfigReference, error) {
+	references := map[string][]ConfigReference{}
+	for _, resource := range resources {
+		objects, err := helper.ListResources(ctx, namespace, resource)
+		if err != nil {
+			return nil, fmt.Errorf("could not list %s in namespace '%s': %w", resource.Resource, namespace, err)
+		}
+		for _, object := range objects {
+			for _, reference := range FindConfigReferences(object) {
+				if reference.Kind == kind {
+					references[reference.Name] = append(references[reference.Name], reference)
+				}
+			}
+		}
+	}
+	return references, nil
+}
+
+// LogConfigReferences logs the objects and fields referencing a ConfigMap or Secret
+func LogConfigReferences(namespace string, references []ConfigReference) {
+	for _, reference := range references {
+		log.Infof("%s %s/%s is used by %s in %s", reference.Kind, namespace, reference.Name, reference.Object, reference.Field)
+	}
+}
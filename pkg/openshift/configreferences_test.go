@@ -0,0 +1,137 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_FindConfigReferences(t *testing.T) {
+	tests := map[string]struct {
+		object   unstructured.Unstructured
+		expected []ConfigReference
+	}{
+		"ShouldFindVolumesAndEnv_InDeploymentTemplate": {
+			object: newUnstructured("Deployment", "ns", "app", map[string]interface{}{
+				"template": map[string]interface{}{"spec": map[string]interface{}{
+					"volumes": []interface{}{
+						map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "app-config"}},
+						map[string]interface{}{"name": "certs", "secret": map[string]interface{}{"secretName": "app-certs"}},
+						map[string]interface{}{"name": "all", "projected": map[string]interface{}{"sources": []interface{}{
+							map[string]interface{}{"configMap": map[string]interface{}{"name": "projected-config"}},
+							map[string]interface{}{"secret": map[string]interface{}{"name": "projected-secret"}},
+						}}},
+					},
+					"initContainers": []interface{}{map[string]interface{}{
+						"name":    "init",
+						"envFrom": []interface{}{map[string]interface{}{"secretRef": map[string]interface{}{"name": "init-env"}}},
+					}},
+					"containers": []interface{}{map[string]interface{}{
+						"name": "app",
+						"env": []interface{}{
+							map[string]interface{}{"name": "PLAIN", "value": "app-config"},
+							map[string]interface{}{"name": "KEY", "valueFrom": map[string]interface{}{
+								"configMapKeyRef": map[string]interface{}{"name": "app-settings", "key": "key"},
+							}},
+						},
+					}},
+					"imagePullSecrets": []interface{}{map[string]interface{}{"name": "pull-secret"}},
+				}},
+			}),
+			expected: []ConfigReference{
+				{Kind: ConfigMapKind, Name: "app-config", Object: "Deployment/app", Field: "spec.template.spec.volumes[config].configMap.name"},
+				{Kind: SecretKind, Name: "app-certs", Object: "Deployment/app", Field: "spec.template.spec.volumes[certs].secret.secretName"},
+				{Kind: ConfigMapKind, Name: "projected-config", Object: "Deployment/app", Field: "spec.template.spec.volumes[all].projected.sources[0].configMap.name"},
+				{Kind: SecretKind, Name: "projected-secret", Object: "Deployment/app", Field: "spec.template.spec.volumes[all].projected.sources[1].secret.name"},
+				{Kind: SecretKind, Name: "init-env", Object: "Deployment/app", Field: "spec.template.spec.initContainers[init].envFrom[0].secretRef.name"},
+				{Kind: ConfigMapKind, Name: "app-settings", Object: "Deployment/app", Field: "spec.template.spec.containers[app].env[KEY].valueFrom.configMapKeyRef.name"},
+				{Kind: SecretKind, Name: "pull-secret", Object: "Deployment/app", Field: "spec.template.spec.imagePullSecrets[pull-secret].name"},
+			},
+		},
+		"ShouldFindSecrets_InStorageVolumes": {
+			object: newUnstructured("StatefulSet", "ns", "db", map[string]interface{}{
+				"template": map[string]interface{}{"spec": map[string]interface{}{
+					"volumes": []interface{}{
+						map[string]interface{}{"name": "csi", "csi": map[string]interface{}{
+							"driver": "secrets-store.csi.k8s.io", "nodePublishSecretRef": map[string]interface{}{"name": "csi-secret"},
+						}},
+						map[string]interface{}{"name": "cephfs", "cephfs": map[string]interface{}{"secretRef": map[string]interface{}{"name": "cephfs-secret"}}},
+						map[string]interface{}{"name": "rbd", "rbd": map[string]interface{}{"secretRef": map[string]interface{}{"name": "rbd-secret"}}},
+						map[string]interface{}{"name": "iscsi", "iscsi": map[string]interface{}{"secretRef": map[string]interface{}{"name": "iscsi-secret"}}},
+						map[string]interface{}{"name": "flex", "flexVolume": map[string]interface{}{"secretRef": map[string]interface{}{"name": "flex-secret"}}},
+						map[string]interface{}{"name": "scaleio", "scaleIO": map[string]interface{}{"secretRef": map[string]interface{}{"name": "scaleio-secret"}}},
+						map[string]interface{}{"name": "storageos", "storageos": map[string]interface{}{"secretRef": map[string]interface{}{"name": "storageos-secret"}}},
+						map[string]interface{}{"name": "azure", "azureFile": map[string]interface{}{"secretName": "azure-secret", "shareName": "data"}},
+					},
+				}},
+			}),
+			expected: []ConfigReference{
+				{Kind: SecretKind, Name: "csi-secret", Object: "StatefulSet/db", Field: "spec.template.spec.volumes[csi].csi.nodePublishSecretRef.name"},
+				{Kind: SecretKind, Name: "cephfs-secret", Object: "StatefulSet/db", Field: "spec.template.spec.volumes[cephfs].cephfs.secretRef.name"},
+				{Kind: SecretKind, Name: "rbd-secret", Object: "StatefulSet/db", Field: "spec.template.spec.volumes[rbd].rbd.secretRef.name"},
+				{Kind: SecretKind, Name: "iscsi-secret", Object: "StatefulSet/db", Field: "spec.template.spec.volumes[iscsi].iscsi.secretRef.name"},
+				{Kind: SecretKind, Name: "flex-secret", Object: "StatefulSet/db", Field: "spec.template.spec.volumes[flex].flexVolume.secretRef.name"},
+				{Kind: SecretKind, Name: "scaleio-secret", Object: "StatefulSet/db", Field: "spec.template.spec.volumes[scaleio].scaleIO.secretRef.name"},
+				{Kind: SecretKind, Name: "storageos-secret", Object: "StatefulSet/db", Field: "spec.template.spec.volumes[storageos].storageos.secretRef.name"},
+				{Kind: SecretKind, Name: "azure-secret", Object: "StatefulSet/db", Field: "spec.template.spec.volumes[azure].azureFile.secretName"},
+			},
+		},
+		"ShouldFindSecrets_InServiceAccount": {
+			object: unstructured.Unstructured{Object: map[string]interface{}{
+				"kind":             "ServiceAccount",
+				"metadata":         map[string]interface{}{"name": "builder"},
+				"secrets":          []interface{}{map[string]interface{}{"name": "builder-token"}},
+				"imagePullSecrets": []interface{}{map[string]interface{}{"name": "builder-dockercfg"}},
+			}},
+			expected: []ConfigReference{
+				{Kind: SecretKind, Name: "builder-token", Object: "ServiceAccount/builder", Field: "secrets[builder-token].name"},
+				{Kind: SecretKind, Name: "builder-dockercfg", Object: "ServiceAccount/builder", Field: "imagePullSecrets[builder-dockercfg].name"},
+			},
+		},
+		"ShouldFindTLSSecrets_InIngressAndRoute": {
+			object: newUnstructured("Ingress", "ns", "web", map[string]interface{}{
+				"tls": []interface{}{map[string]interface{}{"hosts": []interface{}{"example.com"}, "secretName": "web-tls"}},
+			}),
+			expected: []ConfigReference{
+				{Kind: SecretKind, Name: "web-tls", Object: "Ingress/web", Field: "spec.tls[0].secretName"},
+			},
+		},
+		"ShouldFindExternalCertificate_InRoute": {
+			object: newUnstructured("Route", "ns", "web", map[string]interface{}{
+				"tls": map[string]interface{}{"externalCertificate": map[string]interface{}{"name": "route-tls"}},
+			}),
+			expected: []ConfigReference{
+				{Kind: SecretKind, Name: "route-tls", Object: "Route/web", Field: "spec.tls.externalCertificate.name"},
+			},
+		},
+		"ShouldFindSecrets_InBuildConfig": {
+			object: newUnstructured("BuildConfig", "ns", "app", map[string]interface{}{
+				"source": map[string]interface{}{
+					"sourceSecret": map[string]interface{}{"name": "git-credentials"},
+					"configMaps":   []interface{}{map[string]interface{}{"configMap": map[string]interface{}{"name": "build-settings"}}},
+				},
+				"output": map[string]interface{}{"pushSecret": map[string]interface{}{"name": "push-credentials"}},
+			}),
+			expected: []ConfigReference{
+				{Kind: SecretKind, Name: "git-credentials", Object: "BuildConfig/app", Field: "spec.source.sourceSecret.name"},
+				{Kind: SecretKind, Name: "push-credentials", Object: "BuildConfig/app", Field: "spec.output.pushSecret.name"},
+				{Kind: ConfigMapKind, Name: "build-settings", Object: "BuildConfig/app", Field: "spec.source.configMaps[0].configMap.name"},
+			},
+		},
+		"ShouldIgnoreAnnotations": {
+			object: unstructured.Unstructured{Object: map[string]interface{}{
+				"kind": "Pod",
+				"metadata": map[string]interface{}{
+					"name":        "app",
+					"annotations": map[string]interface{}{"config": "app-config"},
+				},
+			}},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.expected, FindConfigReferences(tt.object))
+		})
+	}
+}
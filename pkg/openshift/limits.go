@@ -0,0 +1,89 @@
+package openshift
+
+import (
+	"context"
+
+	"github.com/appuio/seiso/pkg/cleanup"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	core "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// ResourceImageTags is the resource limiting the tags of image streams
+	ResourceImageTags corev1.ResourceName = "openshift.io/image-tags"
+	// ResourceImages is the resource limiting the images referenced by image streams
+	ResourceImages corev1.ResourceName = "openshift.io/images"
+	// LimitTypeImageStream is the type of LimitRange items limiting each image stream
+	LimitTypeImageStream corev1.LimitType = "openshift.io/ImageStream"
+)
+
+// GetImageStreamLimits reads the limits of "openshift.io/image-tags" and "openshift.io/images" from the LimitRanges
+// (type "openshift.io/ImageStream") and the ResourceQuotas of the namespace. If several apply, the lowest one is used.
+func GetImageStreamLimits(ctx context.Context, client core.CoreV1Interface, namespace string) (cleanup.ImageStreamLimits, error) {
+	limits := cleanup.NewUnlimitedImageStreamLimits()
+
+	limitRanges, err := client.LimitRanges(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return limits, err
+	}
+	for _, limitRange := range limitRanges.Items {
+		for _, item := range limitRange.Spec.Limits {
+			if item.Type != LimitTypeImageStream {
+				continue
+			}
+			limits.MaxTags = lowest(limits.MaxTags, limit(item.Max, ResourceImageTags))
+			limits.MaxImages = lowest(limits.MaxImages, limit(item.Max, ResourceImages))
+		}
+	}
+
+	quotas, err := client.ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return limits, err
+	}
+	for _, quota := range quotas.Items {
+		limits.RemainingTags = lowest(limits.RemainingTags, remaining(quota.Status, ResourceImageTags))
+		limits.RemainingImages = lowest(limits.RemainingImages, remaining(quota.Status, ResourceImages))
+	}
+
+	log.WithFields(log.Fields{
+		"namespace": namespace,
+		"limits":    limits,
+	}).Debug("Found image stream limits")
+	return limits, nil
+}
+
+// remaining returns the difference between the hard limit and the usage of the resource in the quota, or Unlimited if
+// the quota does not limit the resource
+func remaining(status corev1.ResourceQuotaStatus, name corev1.ResourceName) int {
+	hard, found := status.Hard[name]
+	if !found {
+		return cleanup.Unlimited
+	}
+	left := hard.Value()
+	if used, found := status.Used[name]; found {
+		left -= used.Value()
+	}
+	if left < 0 {
+		return 0
+	}
+	return int(left)
+}
+
+// limit returns the maximum of the resource in the list, or Unlimited if the list does not limit the resource
+func limit(list corev1.ResourceList, name corev1.ResourceName) int {
+	quantity, found := list[name]
+	if !found {
+		return cleanup.Unlimited
+	}
+	return int(quantity.Value())
+}
+
+// lowest returns the lower one of the limits, considering Unlimited
+func lowest(current, value int) int {
+	if current == cleanup.Unlimited || (value != cleanup.Unlimited && value < current) {
+		return value
+	}
+	return current
+}
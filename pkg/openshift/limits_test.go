@@ -0,0 +1,70 @@
+package openshift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/appuio/seiso/pkg/cleanup"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newLimitRange(name string, limitType corev1.LimitType, max corev1.ResourceList) *corev1.LimitRange {
+	return &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "namespace"},
+		Spec:       corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{Type: limitType, Max: max}}},
+	}
+}
+
+func newResourceQuota(name string, hard, used corev1.ResourceList) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "namespace"},
+		Status:     corev1.ResourceQuotaStatus{Hard: hard, Used: used},
+	}
+}
+
+func Test_GetImageStreamLimits(t *testing.T) {
+	quantity := resource.MustParse
+	tests := map[string]struct {
+		objects  []runtime.Object
+		expected cleanup.ImageStreamLimits
+	}{
+		"GivenNoLimits_ThenReturnUnlimited": {
+			objects: []runtime.Object{
+				newLimitRange("container", corev1.LimitTypeContainer, corev1.ResourceList{corev1.ResourceCPU: quantity("1")}),
+			},
+			expected: cleanup.NewUnlimitedImageStreamLimits(),
+		},
+		"GivenLimitRanges_ThenReturnLowestMax": {
+			objects: []runtime.Object{
+				newLimitRange("tags", LimitTypeImageStream, corev1.ResourceList{ResourceImageTags: quantity("50")}),
+				newLimitRange("images", LimitTypeImageStream, corev1.ResourceList{ResourceImageTags: quantity("60"), ResourceImages: quantity("100")}),
+			},
+			expected: cleanup.ImageStreamLimits{MaxTags: 50, MaxImages: 100, RemainingTags: cleanup.Unlimited, RemainingImages: cleanup.Unlimited},
+		},
+		"GivenResourceQuota_ThenReturnRemaining": {
+			objects: []runtime.Object{
+				newResourceQuota("images",
+					corev1.ResourceList{ResourceImages: quantity("200"), ResourceImageTags: quantity("100")},
+					corev1.ResourceList{ResourceImages: quantity("180"), ResourceImageTags: quantity("120")}),
+			},
+			expected: cleanup.ImageStreamLimits{MaxTags: cleanup.Unlimited, MaxImages: cleanup.Unlimited, RemainingTags: 0, RemainingImages: 20},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.objects...)
+
+			limits, err := GetImageStreamLimits(context.Background(), client.CoreV1(), "namespace")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, limits)
+			assert.Equal(t, tt.expected != cleanup.NewUnlimitedImageStreamLimits(), limits.IsLimited())
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package openshift
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	namespacesResource = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	projectsResource   = schema.GroupVersionResource{Group: "project.openshift.io", Version: "v1", Resource: "projects"}
+)
+
+// ListNamespaces returns the names of all namespaces the user can list. If the user is not allowed to list namespaces,
+// the OpenShift projects are listed instead, which contain the namespaces the user has access to.
+func ListNamespaces(ctx context.Context, client dynamic.Interface) ([]string, error) {
+	list, err := client.Resource(namespacesResource).List(ctx, metav1.ListOptions{})
+	if apierrors.IsForbidden(err) {
+		log.WithError(err).Debug("Not allowed to list namespaces, listing projects instead")
+		list, err = client.Resource(projectsResource).List(ctx, metav1.ListOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	return names, nil
+}
@@ -0,0 +1,57 @@
+package openshift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	test "k8s.io/client-go/testing"
+)
+
+func Test_ListNamespaces(t *testing.T) {
+	project := &unstructured.Unstructured{}
+	project.SetAPIVersion("project.openshift.io/v1")
+	project.SetKind("Project")
+	project.SetName("my-project")
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-1"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-2"}},
+		project,
+	}
+	tests := map[string]struct {
+		forbidden bool
+		expected  []string
+	}{
+		"ShouldListNamespaces": {
+			expected: []string{"ns-1", "ns-2"},
+		},
+		"ShouldListProjects_IfNamespacesAreForbidden": {
+			forbidden: true,
+			expected:  []string{"my-project"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := dynfake.NewSimpleDynamicClientWithCustomListKinds(scheme.Scheme,
+				map[schema.GroupVersionResource]string{projectsResource: "ProjectList"}, objects...)
+			if tt.forbidden {
+				client.PrependReactor("list", "namespaces", func(action test.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(namespacesResource.GroupResource(), "", nil)
+				})
+			}
+
+			namespaces, err := ListNamespaces(context.Background(), client)
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, namespaces)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package openshift
+
+import (
+	"context"
+	"fmt"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	image "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	log "github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetPromotedImageTags retrieves the tags of the target image stream that are promoted to other image streams in the
+// given namespaces, either referenced by the "from" of a spec tag (e.g. by "oc tag") or with an image digest in their
+// history that is still present in the status of another image stream
+func GetPromotedImageTags(ctx context.Context, client image.ImageV1Interface, namespaces []string, target ImageTarget, tags []string) ([]string, error) {
+	activeTags := []string{}
+	for _, namespace := range namespaces {
+		remainingTags := funk.SubtractString(tags, activeTags)
+		if len(remainingTags) == 0 {
+			break
+		}
+		imageStreams, err := client.ImageStreams(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not list image streams in namespace '%s': %w", namespace, err)
+		}
+		for _, imageStream := range imageStreams.Items {
+			if imageStream.Namespace == target.Namespace && imageStream.Name == target.Name {
+				continue
+			}
+			for _, reference := range findPromotionReferences(imageStream) {
+				for _, tag := range remainingTags {
+					if funk.ContainsString(activeTags, tag) || !target.Matches(reference, tag) {
+						continue
+					}
+					log.WithFields(log.Fields{
+						"tag":         tag,
+						"namespace":   imageStream.Namespace,
+						"imageStream": imageStream.Name,
+						"field":       reference.Field,
+					}).Debug("Found promoted image tag")
+					activeTags = append(activeTags, tag)
+				}
+			}
+		}
+	}
+	return activeTags, nil
+}
+
+// findPromotionReferences returns the references of the spec tags of the image stream and the image digests in the
+// history of its status tags
+func findPromotionReferences(imageStream imagev1.ImageStream) []ImageReference {
+	var references []ImageReference
+	for _, specTag := range imageStream.Spec.Tags {
+		from := specTag.From
+		if from == nil || from.Name == "" || (from.Kind != "DockerImage" && from.Kind != "ImageStreamTag" && from.Kind != "ImageStreamImage") {
+			continue
+		}
+		namespace := imageStream.Namespace
+		if from.Namespace != "" {
+			namespace = from.Namespace
+		}
+		references = append(references, ImageReference{
+			Kind:      from.Kind,
+			Namespace: namespace,
+			Name:      from.Name,
+			Field:     fieldPath([]string{"spec", "tags"}, specTag.Name, "from"),
+		})
+	}
+	for _, statusTag := range imageStream.Status.Tags {
+		for i, item := range statusTag.Items {
+			if item.Image == "" {
+				continue
+			}
+			references = append(references, ImageReference{
+				Kind:      ImageDigestKind,
+				Namespace: imageStream.Namespace,
+				Name:      item.Image,
+				Field:     fmt.Sprintf("status.tags[%s].items[%d].image", statusTag.Tag, i),
+			})
+		}
+	}
+	return references
+}
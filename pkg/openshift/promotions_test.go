@@ -0,0 +1,72 @@
+package openshift
+
+import (
+	"context"
+	"testing"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/openshift/client-go/image/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPromotionTestImageStream(namespace, name string, from []corev1.ObjectReference, images ...string) *imagev1.ImageStream {
+	imageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	for i := range from {
+		imageStream.Spec.Tags = append(imageStream.Spec.Tags, imagev1.TagReference{Name: from[i].Name, From: &from[i]})
+	}
+	for _, image := range images {
+		imageStream.Status.Tags = append(imageStream.Status.Tags, imagev1.NamedTagEventList{
+			Tag:   "promoted",
+			Items: []imagev1.TagEvent{{Image: image}},
+		})
+	}
+	return imageStream
+}
+
+func Test_GetPromotedImageTags(t *testing.T) {
+	target := ImageTarget{
+		Namespace: "build",
+		Name:      "app",
+		Digests: map[string][]string{
+			"t1": {"sha256:t1"}, "t2": {"sha256:t2"}, "t3": {"sha256:t3"}, "t4": {"sha256:t4", "sha256:t4-old"}, "t5": {"sha256:t5"},
+		},
+	}
+	client := fake.NewSimpleClientset(
+		// the image stream itself is ignored
+		newPromotionTestImageStream("build", "app", nil, "sha256:t5"),
+		newPromotionTestImageStream("prod", "app", []corev1.ObjectReference{
+			{Kind: "ImageStreamTag", Namespace: "build", Name: "app:t1"},
+			{Kind: "ImageStreamImage", Namespace: "build", Name: "app@sha256:t2"},
+			{Kind: "ImageStreamTag", Name: "app:t3"},
+		}),
+		newPromotionTestImageStream("test", "app", nil, "sha256:t4-old"),
+		newPromotionTestImageStream("other", "app", nil, "sha256:t3"),
+	)
+	tests := map[string]struct {
+		namespaces []string
+		expected   []string
+	}{
+		"GivenSpecTagsAndStatusDigests_ThenReturnPromotedTags": {
+			namespaces: []string{"build", "prod", "test"},
+			expected:   []string{"t1", "t2", "t4"},
+		},
+		"GivenImageInNotSearchedNamespace_ThenIgnoreIt": {
+			namespaces: []string{"prod"},
+			expected:   []string{"t1", "t2"},
+		},
+		"GivenSameNamespace_ThenIgnoreImageStreamItself": {
+			namespaces: []string{"build"},
+			expected:   []string{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			promotedTags, err := GetPromotedImageTags(context.Background(), client.ImageV1(), tt.namespaces, target, []string{"t1", "t2", "t3", "t4", "t5"})
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, promotedTags)
+		})
+	}
+}
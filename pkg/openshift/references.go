@@ -0,0 +1,389 @@
+package openshift
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/appuio/seiso/pkg/kubernetes"
+	log "github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type (
+	// ImageReference is a reference to an image found in a field of a Kubernetes object
+	ImageReference struct {
+		// Kind is either DockerImage, ImageStreamTag, ImageStreamImage or ImageDigestKind
+		Kind string
+		// Namespace is the namespace of the referenced image stream. Defaults to the namespace of the object.
+		Namespace string
+		// Name is the pull spec of a DockerImage, or the name of an ImageStreamTag ("image:tag") or ImageStreamImage
+		// ("image@digest"), or the digest of a running image
+		Name string
+		// Field is the path of the field containing the reference, e.g. "spec.template.spec.containers[app].image"
+		Field string
+	}
+	// DockerImageReference is a parsed image pull spec, e.g. "registry:5000/namespace/name:tag@digest"
+	DockerImageReference struct {
+		Registry  string
+		Namespace string
+		Name      string
+		Tag       string
+		Digest    string
+	}
+	// ImageTarget identifies the image whose tags are searched for in image references
+	ImageTarget struct {
+		// Registry restricts pull specs to the given registry host. If empty, the image is an image stream, which is
+		// referenced through different registry hostnames (internal service, route) and as ImageStreamTag.
+		Registry string
+		// Namespace is the namespace of the image stream, or the repository path without the image name
+		Namespace string
+		// Name is the name of the image stream or the last element of the repository path
+		Name string
+		// Digests maps the tags to the digests of their images. References by digest count for these tags.
+		Digests map[string][]string
+	}
+)
+
+const (
+	// ReferenceModeStructured compares the image fields of resources by full reference and digest
+	ReferenceModeStructured = "structured"
+	// ReferenceModeSubstring searches all string fields of resources for "image:tag"
+	ReferenceModeSubstring = "substring"
+	// ImageDigestKind is the kind of references to the digest of an image, regardless of the image name, e.g. of a
+	// running image or of an image in the history of another image stream
+	ImageDigestKind = "ImageDigest"
+	// dockerHubRegistry is the registry of pull specs without host
+	dockerHubRegistry = "docker.io"
+)
+
+var (
+	// ImageReferenceResources are the resources searched for image references in the structured reference mode
+	ImageReferenceResources = append(append([]schema.GroupVersionResource{}, PredefinedResources...),
+		schema.GroupVersionResource{Group: "build.openshift.io", Version: "v1", Resource: "buildconfigs"},
+		schema.GroupVersionResource{Group: "image.openshift.io", Version: "v1", Resource: "imagestreams"},
+	)
+	podResource  = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	podSpecPaths = [][]string{
+		{"spec"},
+		{"spec", "template", "spec"},
+		{"spec", "jobTemplate", "spec", "template", "spec"},
+	}
+	objectReferencePaths = [][]string{
+		{"spec", "strategy", "sourceStrategy", "from"},
+		{"spec", "strategy", "dockerStrategy", "from"},
+		{"spec", "strategy", "customStrategy", "from"},
+		{"spec", "output", "to"},
+	}
+)
+
+// IsValidReferenceMode returns true if the mode is a known reference mode
+func IsValidReferenceMode(mode string) bool {
+	return mode == ReferenceModeStructured || mode == ReferenceModeSubstring
+}
+
+// NormalizeRegistry returns the registry host in the spelling used for comparisons: the default HTTPS and HTTP ports
+// are dropped, and the Docker Hub aliases as well as the empty registry of pull specs without host become "docker.io".
+func NormalizeRegistry(registry string) string {
+	registry = strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(registry), ":443"), ":80")
+	switch registry {
+	case "", "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHubRegistry
+	}
+	return registry
+}
+
+// ParseDockerImageReference parses an image pull spec. The first path element is the registry if it contains a dot or
+// a port, or is "localhost". A reference without tag and digest refers to the "latest" tag.
+func ParseDockerImageReference(spec string) DockerImageReference {
+	reference := DockerImageReference{}
+	if i := strings.Index(spec, "@"); i >= 0 {
+		reference.Digest = spec[i+1:]
+		spec = spec[:i]
+	}
+	if i := strings.LastIndex(spec, ":"); i > strings.LastIndex(spec, "/") {
+		reference.Tag = spec[i+1:]
+		spec = spec[:i]
+	}
+	paths := strings.Split(spec, "/")
+	if len(paths) > 1 && (strings.ContainsAny(paths[0], ".:") || paths[0] == "localhost") {
+		reference.Registry = paths[0]
+		paths = paths[1:]
+	}
+	reference.Name = paths[len(paths)-1]
+	reference.Namespace = strings.Join(paths[:len(paths)-1], "/")
+	if reference.Tag == "" && reference.Digest == "" {
+		reference.Tag = "latest"
+	}
+	return reference
+}
+
+// FindImageReferences returns the image references in the image fields of the object: containers, init containers
+// and ephemeral containers of pod specs (also in templates), DeploymentConfig and BuildConfig image change triggers,
+// BuildConfig strategy images, inputs and output, ImageStream spec tags and the digests of running Pod containers.
+func FindImageReferences(object unstructured.Unstructured) []ImageReference {
+	namespace := object.GetNamespace()
+	references := FindRunningImageDigests(object)
+
+	for _, podSpecPath := range podSpecPaths {
+		for _, containerField := range []string{"containers", "initContainers", "ephemeralContainers"} {
+			containers, _, _ := unstructured.NestedSlice(object.Object, append(podSpecPath, containerField)...)
+			for i, container := range containers {
+				containerMap, ok := container.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				image, _, _ := unstructured.NestedString(containerMap, "image")
+				if image == "" {
+					continue
+				}
+				references = append(references, ImageReference{
+					Kind:      "DockerImage",
+					Namespace: namespace,
+					Name:      image,
+					Field:     fieldPath(append(podSpecPath, containerField), elementName(containerMap, i), "image"),
+				})
+			}
+		}
+	}
+
+	for _, referencePath := range objectReferencePaths {
+		from, found, _ := unstructured.NestedMap(object.Object, referencePath...)
+		if found {
+			references = appendObjectReference(references, from, namespace, strings.Join(referencePath, "."))
+		}
+	}
+
+	triggers, _, _ := unstructured.NestedSlice(object.Object, "spec", "triggers")
+	for i, trigger := range triggers {
+		triggerMap, ok := trigger.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		prefix := fieldPath([]string{"spec", "triggers"}, strconv.Itoa(i))
+		// DeploymentConfig triggers
+		if from, found, _ := unstructured.NestedMap(triggerMap, "imageChangeParams", "from"); found {
+			references = appendObjectReference(references, from, namespace, prefix+".imageChangeParams.from")
+		}
+		if image, _, _ := unstructured.NestedString(triggerMap, "imageChangeParams", "lastTriggeredImage"); image != "" {
+			references = append(references, ImageReference{
+				Kind:      "DockerImage",
+				Namespace: namespace,
+				Name:      image,
+				Field:     prefix + ".imageChangeParams.lastTriggeredImage",
+			})
+		}
+		// BuildConfig triggers
+		if from, found, _ := unstructured.NestedMap(triggerMap, "imageChange", "from"); found {
+			references = appendObjectReference(references, from, namespace, prefix+".imageChange.from")
+		}
+	}
+
+	for _, listPath := range [][]string{{"spec", "tags"}, {"spec", "source", "images"}} {
+		items, _, _ := unstructured.NestedSlice(object.Object, listPath...)
+		for i, item := range items {
+			itemMap, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if from, found, _ := unstructured.NestedMap(itemMap, "from"); found {
+				references = appendObjectReference(references, from, namespace, fieldPath(listPath, elementName(itemMap, i), "from"))
+			}
+		}
+	}
+	return references
+}
+
+// FindRunningImageDigests returns the digests of the images of the containers of a Pod, read from the imageID of the
+// container statuses. Pods that have terminated are ignored.
+func FindRunningImageDigests(pod unstructured.Unstructured) []ImageReference {
+	phase, _, _ := unstructured.NestedString(pod.Object, "status", "phase")
+	if phase == "Succeeded" || phase == "Failed" {
+		return nil
+	}
+	var references []ImageReference
+	for _, statusField := range []string{"containerStatuses", "initContainerStatuses", "ephemeralContainerStatuses"} {
+		statuses, _, _ := unstructured.NestedSlice(pod.Object, "status", statusField)
+		for i, status := range statuses {
+			statusMap, ok := status.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// e.g. "docker-pullable://registry/namespace/app@sha256:...", IDs without digest are local image IDs
+			imageID, _, _ := unstructured.NestedString(statusMap, "imageID")
+			at := strings.LastIndex(imageID, "@")
+			if at < 0 {
+				continue
+			}
+			references = append(references, ImageReference{
+				Kind:      ImageDigestKind,
+				Namespace: pod.GetNamespace(),
+				Name:      imageID[at+1:],
+				Field:     fieldPath([]string{"status", statusField}, elementName(statusMap, i), "imageID"),
+			})
+		}
+	}
+	return references
+}
+
+// appendObjectReference adds an ObjectReference of kind DockerImage, ImageStreamTag or ImageStreamImage
+func appendObjectReference(references []ImageReference, from map[string]interface{}, namespace, field string) []ImageReference {
+	kind, _, _ := unstructured.NestedString(from, "kind")
+	name, _, _ := unstructured.NestedString(from, "name")
+	if name == "" || (kind != "DockerImage" && kind != "ImageStreamTag" && kind != "ImageStreamImage") {
+		return references
+	}
+	if referenceNamespace, _, _ := unstructured.NestedString(from, "namespace"); referenceNamespace != "" {
+		namespace = referenceNamespace
+	}
+	return append(references, ImageReference{Kind: kind, Namespace: namespace, Name: name, Field: field})
+}
+
+func elementName(element map[string]interface{}, index int) string {
+	if name, _, _ := unstructured.NestedString(element, "name"); name != "" {
+		return name
+	}
+	return strconv.Itoa(index)
+}
+
+func fieldPath(path []string, element string, fields ...string) string {
+	return strings.Join(append([]string{strings.Join(path, ".") + "[" + element + "]"}, fields...), ".")
+}
+
+// Matches returns true if the reference points to the tag of the target image, either by its full name or by the
+// digest of one of the images of the tag
+func (t ImageTarget) Matches(reference ImageReference, tag string) bool {
+	switch reference.Kind {
+	case "DockerImage":
+		image := ParseDockerImageReference(reference.Name)
+		registry := NormalizeRegistry(image.Registry)
+		if t.Registry != "" && registry != NormalizeRegistry(t.Registry) {
+			return false
+		}
+		namespace := image.Namespace
+		switch {
+		case namespace == "" && image.Registry == "" && t.Registry == "":
+			// short names are resolved to image streams of the same namespace
+			namespace = reference.Namespace
+		case namespace == "" && t.Registry != "" && registry == dockerHubRegistry:
+			// official Docker Hub images are in the "library" namespace
+			namespace = "library"
+		}
+		if namespace != t.Namespace || image.Name != t.Name {
+			return false
+		}
+		if image.Digest != "" && funk.ContainsString(t.Digests[tag], image.Digest) {
+			return true
+		}
+		return image.Tag == tag
+	case "ImageStreamTag":
+		if t.Registry != "" || reference.Namespace != t.Namespace {
+			return false
+		}
+		name, referencedTag := reference.Name, "latest"
+		if i := strings.LastIndex(name, ":"); i >= 0 {
+			name, referencedTag = name[:i], name[i+1:]
+		}
+		return name == t.Name && referencedTag == tag
+	case "ImageStreamImage":
+		if t.Registry != "" || reference.Namespace != t.Namespace {
+			return false
+		}
+		i := strings.Index(reference.Name, "@")
+		return i >= 0 && reference.Name[:i] == t.Name && funk.ContainsString(t.Digests[tag], reference.Name[i+1:])
+	case ImageDigestKind:
+		// the image is running, no matter through which name it was pulled
+		return funk.ContainsString(t.Digests[tag], reference.Name)
+	}
+	return false
+}
+
+// SubstringResources returns the resources searched in the substring reference mode
+func (o ActiveImageOptions) SubstringResources() []schema.GroupVersionResource {
+	return append(append([]schema.GroupVersionResource{}, PredefinedResources...), o.Resources...)
+}
+
+// ReferenceResources returns the resources searched in the structured reference mode
+func (o ActiveImageOptions) ReferenceResources() []schema.GroupVersionResource {
+	return append(append([]schema.GroupVersionResource{}, ImageReferenceResources...), o.Resources...)
+}
+
+// SearchNamespaces returns the namespace of the image followed by the additional namespaces of the options
+func (o ActiveImageOptions) SearchNamespaces(namespace string) []string {
+	namespaces := []string{namespace}
+	for _, otherNamespace := range o.Namespaces {
+		if !funk.ContainsString(namespaces, otherNamespace) {
+			namespaces = append(namespaces, otherNamespace)
+		}
+	}
+	return namespaces
+}
+
+// GetRunningImageTags retrieves the tags of the target image whose history contains the digest of an image running in
+// a Pod of the given namespaces
+func GetRunningImageTags(ctx context.Context, helper kubernetes.Kubernetes, namespaces []string, target ImageTarget, tags []string) ([]string, error) {
+	activeTags := []string{}
+	for _, namespace := range namespaces {
+		remainingTags := funk.SubtractString(tags, activeTags)
+		if len(remainingTags) == 0 {
+			break
+		}
+		pods, err := helper.ListResources(ctx, namespace, podResource)
+		if err != nil {
+			return nil, fmt.Errorf("could not list pods in namespace '%s': %w", namespace, err)
+		}
+		for _, pod := range pods {
+			activeTags = appendMatchingTags(activeTags, target, FindRunningImageDigests(pod), pod, remainingTags)
+		}
+	}
+	return activeTags, nil
+}
+
+// GetReferencedImageTags retrieves the tags of the target image that are referenced in the image fields of the
+// resources in the given namespaces
+func GetReferencedImageTags(ctx context.Context, helper kubernetes.Kubernetes, resources []schema.GroupVersionResource, namespaces []string, target ImageTarget, tags []string) ([]string, error) {
+	log.WithFields(log.Fields{
+		"namespaces": namespaces,
+		"imageName":  target.Namespace + "/" + target.Name,
+		"imageTags":  tags,
+	}).Debug("Looking for image references")
+	activeTags := []string{}
+	for _, namespace := range namespaces {
+		for _, resource := range resources {
+			remainingTags := funk.SubtractString(tags, activeTags)
+			if len(remainingTags) == 0 {
+				return activeTags, nil
+			}
+			objects, err := helper.ListResources(ctx, namespace, resource)
+			if err != nil {
+				return nil, fmt.Errorf("could not list %s in namespace '%s': %w", resource.Resource, namespace, err)
+			}
+			for _, object := range objects {
+				activeTags = appendMatchingTags(activeTags, target, FindImageReferences(object), object, remainingTags)
+			}
+		}
+	}
+	return activeTags, nil
+}
+
+// appendMatchingTags adds the tags that are matched by one of the references of the object
+func appendMatchingTags(activeTags []string, target ImageTarget, references []ImageReference, object unstructured.Unstructured, tags []string) []string {
+	for _, reference := range references {
+		for _, tag := range tags {
+			if funk.ContainsString(activeTags, tag) || !target.Matches(reference, tag) {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"tag":       tag,
+				"namespace": object.GetNamespace(),
+				"object":    object.GetKind() + "/" + object.GetName(),
+				"field":     reference.Field,
+			}).Debug("Found image reference")
+			activeTags = append(activeTags, tag)
+		}
+	}
+	return activeTags
+}
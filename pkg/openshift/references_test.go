@@ -0,0 +1,292 @@
+package openshift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newUnstructured(kind, namespace, name string, spec map[string]interface{}) unstructured.Unstructured {
+	return unstructured.Unstructured{Object: map[string]interface{}{
+		"kind":     kind,
+		"metadata": map[string]interface{}{"name": name, "namespace": namespace},
+		"spec":     spec,
+	}}
+}
+
+func Test_ParseDockerImageReference(t *testing.T) {
+	tests := map[string]DockerImageReference{
+		"app":                             {Name: "app", Tag: "latest"},
+		"namespace/app:v1":                {Namespace: "namespace", Name: "app", Tag: "v1"},
+		"registry:5000/namespace/app:v1":  {Registry: "registry:5000", Namespace: "namespace", Name: "app", Tag: "v1"},
+		"quay.io/org/team/app@sha256:abc": {Registry: "quay.io", Namespace: "org/team", Name: "app", Digest: "sha256:abc"},
+		"localhost/app:v1@sha256:abc":     {Registry: "localhost", Name: "app", Tag: "v1", Digest: "sha256:abc"},
+	}
+	for spec, expected := range tests {
+		t.Run(spec, func(t *testing.T) {
+			assert.Equal(t, expected, ParseDockerImageReference(spec))
+		})
+	}
+}
+
+func Test_FindImageReferences(t *testing.T) {
+	tests := map[string]struct {
+		object   unstructured.Unstructured
+		expected []ImageReference
+	}{
+		"ShouldFindContainerImages_InPod": {
+			object: newUnstructured("Pod", "ns", "pod", map[string]interface{}{
+				"initContainers":      []interface{}{map[string]interface{}{"name": "init", "image": "ns/init:v1"}},
+				"containers":          []interface{}{map[string]interface{}{"name": "app", "image": "ns/app:v1"}},
+				"ephemeralContainers": []interface{}{map[string]interface{}{"name": "debug", "image": "busybox"}},
+			}),
+			expected: []ImageReference{
+				{Kind: "DockerImage", Namespace: "ns", Name: "ns/app:v1", Field: "spec.containers[app].image"},
+				{Kind: "DockerImage", Namespace: "ns", Name: "ns/init:v1", Field: "spec.initContainers[init].image"},
+				{Kind: "DockerImage", Namespace: "ns", Name: "busybox", Field: "spec.ephemeralContainers[debug].image"},
+			},
+		},
+		"ShouldFindContainerImages_InCronJobTemplate": {
+			object: newUnstructured("CronJob", "ns", "job", map[string]interface{}{
+				"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{
+					"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "job", "image": "ns/job:v1"}}},
+				}}},
+			}),
+			expected: []ImageReference{
+				{Kind: "DockerImage", Namespace: "ns", Name: "ns/job:v1", Field: "spec.jobTemplate.spec.template.spec.containers[job].image"},
+			},
+		},
+		"ShouldFindImageChangeTriggers_InDeploymentConfig": {
+			object: newUnstructured("DeploymentConfig", "ns", "dc", map[string]interface{}{
+				"triggers": []interface{}{
+					map[string]interface{}{"type": "ConfigChange"},
+					map[string]interface{}{"type": "ImageChange", "imageChangeParams": map[string]interface{}{
+						"from":               map[string]interface{}{"kind": "ImageStreamTag", "name": "app:v1", "namespace": "build"},
+						"lastTriggeredImage": "registry:5000/build/app@sha256:abc",
+					}},
+				},
+			}),
+			expected: []ImageReference{
+				{Kind: "ImageStreamTag", Namespace: "build", Name: "app:v1", Field: "spec.triggers[1].imageChangeParams.from"},
+				{Kind: "DockerImage", Namespace: "ns", Name: "registry:5000/build/app@sha256:abc", Field: "spec.triggers[1].imageChangeParams.lastTriggeredImage"},
+			},
+		},
+		"ShouldFindFromAndTo_InBuildConfig": {
+			object: newUnstructured("BuildConfig", "ns", "bc", map[string]interface{}{
+				"strategy": map[string]interface{}{"dockerStrategy": map[string]interface{}{
+					"from": map[string]interface{}{"kind": "ImageStreamTag", "name": "base:v1"},
+				}},
+				"output": map[string]interface{}{"to": map[string]interface{}{"kind": "ImageStreamTag", "name": "app:latest"}},
+				"source": map[string]interface{}{"images": []interface{}{map[string]interface{}{
+					"from": map[string]interface{}{"kind": "DockerImage", "name": "quay.io/org/assets:v2"},
+				}}},
+			}),
+			expected: []ImageReference{
+				{Kind: "ImageStreamTag", Namespace: "ns", Name: "base:v1", Field: "spec.strategy.dockerStrategy.from"},
+				{Kind: "ImageStreamTag", Namespace: "ns", Name: "app:latest", Field: "spec.output.to"},
+				{Kind: "DockerImage", Namespace: "ns", Name: "quay.io/org/assets:v2", Field: "spec.source.images[0].from"},
+			},
+		},
+		"ShouldFindSpecTags_InImageStream": {
+			object: newUnstructured("ImageStream", "ns", "app", map[string]interface{}{
+				"tags": []interface{}{
+					map[string]interface{}{"name": "prod", "from": map[string]interface{}{"kind": "ImageStreamImage", "name": "app@sha256:abc"}},
+					map[string]interface{}{"name": "local"},
+				},
+			}),
+			expected: []ImageReference{
+				{Kind: "ImageStreamImage", Namespace: "ns", Name: "app@sha256:abc", Field: "spec.tags[prod].from"},
+			},
+		},
+		"ShouldIgnoreAnnotationsAndEnv": {
+			object: unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"annotations": map[string]interface{}{"image": "ns/app:v1"}},
+				"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{
+					"env": []interface{}{map[string]interface{}{"name": "IMAGE", "value": "ns/app:v1"}},
+				}}},
+			}},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.expected, FindImageReferences(tt.object))
+		})
+	}
+}
+
+func Test_FindRunningImageDigests(t *testing.T) {
+	pod := newUnstructured("Pod", "ns", "pod", nil)
+	pod.Object["status"] = map[string]interface{}{
+		"phase": "Running",
+		"containerStatuses": []interface{}{
+			map[string]interface{}{"name": "app", "imageID": "docker-pullable://registry:5000/ns/app@sha256:abc"},
+			map[string]interface{}{"name": "sidecar", "imageID": "sha256:local"},
+		},
+		"initContainerStatuses": []interface{}{
+			map[string]interface{}{"name": "init", "imageID": "registry:5000/ns/init@sha256:def"},
+		},
+	}
+
+	assert.Equal(t, []ImageReference{
+		{Kind: ImageDigestKind, Namespace: "ns", Name: "sha256:abc", Field: "status.containerStatuses[app].imageID"},
+		{Kind: ImageDigestKind, Namespace: "ns", Name: "sha256:def", Field: "status.initContainerStatuses[init].imageID"},
+	}, FindRunningImageDigests(pod))
+
+	pod.Object["status"].(map[string]interface{})["phase"] = "Succeeded"
+	assert.Empty(t, FindRunningImageDigests(pod))
+}
+
+func Test_ImageTarget_Matches(t *testing.T) {
+	imageStream := ImageTarget{Namespace: "ns", Name: "app", Digests: map[string][]string{"v1": {"sha256:new", "sha256:old"}}}
+	registry := ImageTarget{Registry: "registry.example.com", Namespace: "team", Name: "app"}
+	dockerHub := ImageTarget{Registry: "registry-1.docker.io:443", Namespace: "library", Name: "nginx"}
+	tests := map[string]struct {
+		target    ImageTarget
+		reference ImageReference
+		tag       string
+		expected  bool
+	}{
+		"ShouldMatch_FullReference": {
+			target:    imageStream,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "other", Name: "image-registry.svc:5000/ns/app:v1"},
+			tag:       "v1",
+			expected:  true,
+		},
+		"ShouldNotMatch_TagPrefix": {
+			target:    imageStream,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "ns/app:v1beta"},
+			tag:       "v1",
+		},
+		"ShouldNotMatch_ImageWithSameSuffix": {
+			target:    imageStream,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "ns/myapp:v1"},
+			tag:       "v1",
+		},
+		"ShouldMatch_ShortName_InSameNamespace": {
+			target:    imageStream,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "app:v1"},
+			tag:       "v1",
+			expected:  true,
+		},
+		"ShouldMatch_DigestOfOlderImage": {
+			target:    imageStream,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "image-registry.svc:5000/ns/app@sha256:old"},
+			tag:       "v1",
+			expected:  true,
+		},
+		"ShouldMatch_ImageStreamTag": {
+			target:    imageStream,
+			reference: ImageReference{Kind: "ImageStreamTag", Namespace: "ns", Name: "app:v1"},
+			tag:       "v1",
+			expected:  true,
+		},
+		"ShouldNotMatch_ImageStreamTag_InOtherNamespace": {
+			target:    imageStream,
+			reference: ImageReference{Kind: "ImageStreamTag", Namespace: "other", Name: "app:v1"},
+			tag:       "v1",
+		},
+		"ShouldMatch_ImageStreamImage": {
+			target:    imageStream,
+			reference: ImageReference{Kind: "ImageStreamImage", Namespace: "ns", Name: "app@sha256:new"},
+			tag:       "v1",
+			expected:  true,
+		},
+		"ShouldMatch_RunningDigest_OfAnyImageName": {
+			target:    imageStream,
+			reference: ImageReference{Kind: ImageDigestKind, Namespace: "other", Name: "sha256:old"},
+			tag:       "v1",
+			expected:  true,
+		},
+		"ShouldMatch_RegistryImage": {
+			target:    registry,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "registry.example.com/team/app:v1"},
+			tag:       "v1",
+			expected:  true,
+		},
+		"ShouldMatch_RegistryImage_WithDefaultPort": {
+			target:    registry,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "registry.example.com:443/team/app:v1"},
+			tag:       "v1",
+			expected:  true,
+		},
+		"ShouldNotMatch_RegistryImage_WithOtherPort": {
+			target:    registry,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "registry.example.com:5000/team/app:v1"},
+			tag:       "v1",
+		},
+		"ShouldMatch_DockerHubImage_WithoutRegistry": {
+			target:    dockerHub,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "nginx:v1"},
+			tag:       "v1",
+			expected:  true,
+		},
+		"ShouldMatch_DockerHubImage_WithAlias": {
+			target:    dockerHub,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "docker.io/library/nginx:v1"},
+			tag:       "v1",
+			expected:  true,
+		},
+		"ShouldNotMatch_DockerHubImage_InOtherNamespace": {
+			target:    dockerHub,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "team/nginx:v1"},
+			tag:       "v1",
+		},
+		"ShouldNotMatch_RegistryImage_InOtherRegistry": {
+			target:    registry,
+			reference: ImageReference{Kind: "DockerImage", Namespace: "ns", Name: "quay.io/team/app:v1"},
+			tag:       "v1",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.target.Matches(tt.reference, tt.tag))
+		})
+	}
+}
+
+func Test_GetReferencedImageTags(t *testing.T) {
+	helper := new(MockHelper)
+	for _, resource := range ImageReferenceResources {
+		var objects []unstructured.Unstructured
+		if resource.Resource == "pods" {
+			objects = []unstructured.Unstructured{newUnstructured("Pod", "ns", "pod", map[string]interface{}{
+				"containers": []interface{}{map[string]interface{}{"name": "app", "image": "ns/app:abcdef"}},
+			})}
+		}
+		if resource.Resource == "deploymentconfigs" {
+			objects = []unstructured.Unstructured{newUnstructured("DeploymentConfig", "ns", "dc", map[string]interface{}{
+				"template": map[string]interface{}{"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"previous": "ns/app:abc"},
+				}},
+			})}
+		}
+		helper.On("ListResources", "ns", resource).Return(objects, nil)
+	}
+
+	activeTags, err := GetReferencedImageTags(context.Background(), helper, ImageReferenceResources, []string{"ns"}, ImageTarget{Namespace: "ns", Name: "app"}, []string{"abc", "abcdef"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abcdef"}, activeTags)
+	helper.AssertNumberOfCalls(t, "ListResources", len(ImageReferenceResources))
+	helper.AssertNotCalled(t, "ResourceContains", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func Test_NormalizeRegistry(t *testing.T) {
+	tests := map[string]string{
+		"":                          "docker.io",
+		"docker.io":                 "docker.io",
+		"index.docker.io":           "docker.io",
+		"registry-1.docker.io:443":  "docker.io",
+		"Registry.Example.com:443":  "registry.example.com",
+		"registry.example.com:80":   "registry.example.com",
+		"registry.example.com:5000": "registry.example.com:5000",
+	}
+	for registry, expected := range tests {
+		t.Run(registry, func(t *testing.T) {
+			assert.Equal(t, expected, NormalizeRegistry(registry))
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package openshift
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/appuio/seiso/pkg/cleanup"
+	"github.com/appuio/seiso/pkg/kubernetes"
+	imagev1 "github.com/openshift/api/image/v1"
+	image "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+	log "github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ImageStreamRepository provides the tags of the ImageStreams in a namespace
+type ImageStreamRepository struct {
+	client        image.ImageV1Interface
+	helper        kubernetes.Kubernetes
+	namespace     string
+	activeOptions ActiveImageOptions
+	// tags caches the tags of the image streams read by GetTags, their digests are used to find references by digest
+	tags map[string][]cleanup.ImageTag
+}
+
+// NewImageStreamRepository creates a new ImageStreamRepository for the given namespace
+func NewImageStreamRepository(client image.ImageV1Interface, helper kubernetes.Kubernetes, namespace string, activeOptions ActiveImageOptions) *ImageStreamRepository {
+	return &ImageStreamRepository{
+		client:        client,
+		helper:        helper,
+		namespace:     namespace,
+		activeOptions: activeOptions,
+		tags:          map[string][]cleanup.ImageTag{},
+	}
+}
+
+// GetTags returns the tags of an image stream with the images in their history, newest first
+func (r *ImageStreamRepository) GetTags(ctx context.Context, imageStreamName string) ([]cleanup.ImageTag, error) {
+	imageStream, err := r.client.ImageStreams(r.namespace).Get(ctx, imageStreamName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	imageTags := make([]cleanup.ImageTag, 0, len(imageStream.Status.Tags))
+	for _, tagEvents := range imageStream.Status.Tags {
+		imageTags = append(imageTags, newImageTag(tagEvents))
+	}
+	r.tags[imageStreamName] = imageTags
+	return imageTags, nil
+}
+
+// newImageTag converts the history of an image stream tag, the tag was last updated by its most recent event
+func newImageTag(tagEvents imagev1.NamedTagEventList) cleanup.ImageTag {
+	imageTag := cleanup.ImageTag{Name: tagEvents.Tag}
+	for _, item := range tagEvents.Items {
+		if item.Image != "" {
+			imageTag.Digests = append(imageTag.Digests, item.Image)
+		}
+		if imageTag.Created.Before(item.Created.Time) {
+			imageTag.Created = item.Created.Time
+		}
+	}
+	return imageTag
+}
+
+// GetCachedTags returns the tags read by GetTags, so that the image stream is not read again. The tags are read if
+// GetTags has not been called for the image stream since it was last changed.
+func (r *ImageStreamRepository) GetCachedTags(ctx context.Context, imageStreamName string) ([]cleanup.ImageTag, error) {
+	if imageTags, found := r.tags[imageStreamName]; found {
+		return imageTags, nil
+	}
+	return r.GetTags(ctx, imageStreamName)
+}
+
+// GetActiveTags returns the tags of the image stream that are referenced in the namespace or in the additional
+// namespaces of the options, or promoted to image streams of the promotion namespaces. References by digest and images
+// running in Pods count for all tags whose history contains the digest.
+func (r *ImageStreamRepository) GetActiveTags(ctx context.Context, imageStreamName string, tags []string) ([]string, error) {
+	imageTags, err := r.GetCachedTags(ctx, imageStreamName)
+	if err != nil {
+		return nil, err
+	}
+	target := ImageTarget{
+		Namespace: r.namespace,
+		Name:      imageStreamName,
+		Digests:   cleanup.TagDigests(imageTags),
+	}
+	activeTags, err := r.getReferencedTags(ctx, target, tags)
+	if err != nil {
+		return nil, err
+	}
+	return r.appendPromotedTags(ctx, activeTags, target, tags)
+}
+
+// getReferencedTags returns the tags of the target that are referenced or running according to the reference mode
+func (r *ImageStreamRepository) getReferencedTags(ctx context.Context, target ImageTarget, tags []string) ([]string, error) {
+	namespaces := r.activeOptions.SearchNamespaces(r.namespace)
+	if r.activeOptions.ReferenceMode != ReferenceModeSubstring {
+		return GetReferencedImageTags(ctx, r.helper, r.activeOptions.ReferenceResources(), namespaces, target, tags)
+	}
+	activeTags, err := GetActiveImageStreamTagsInNamespaces(ctx, r.helper, r.activeOptions.SubstringResources(), r.namespace, target.Name, tags, r.activeOptions.Namespaces)
+	if err != nil {
+		return nil, err
+	}
+	runningTags, err := GetRunningImageTags(ctx, r.helper, namespaces, target, funk.SubtractString(tags, activeTags))
+	if err != nil {
+		return nil, err
+	}
+	return append(activeTags, runningTags...), nil
+}
+
+// appendPromotedTags adds the remaining tags that are promoted to image streams in the promotion namespaces
+func (r *ImageStreamRepository) appendPromotedTags(ctx context.Context, activeTags []string, target ImageTarget, tags []string) ([]string, error) {
+	remainingTags := funk.SubtractString(tags, activeTags)
+	if len(r.activeOptions.PromotionNamespaces) == 0 || len(remainingTags) == 0 {
+		return activeTags, nil
+	}
+	promotedTags, err := GetPromotedImageTags(ctx, r.client, r.activeOptions.PromotionNamespaces, target, remainingTags)
+	if err != nil {
+		return nil, err
+	}
+	return append(activeTags, promotedTags...), nil
+}
+
+// DeleteTags deletes the image stream tags. All tags are attempted, failures are logged and reported as error.
+func (r *ImageStreamRepository) DeleteTags(ctx context.Context, imageStreamName string, tags []string) error {
+	delete(r.tags, imageStreamName)
+	failed := 0
+	for _, tag := range tags {
+		log.Infof("Deleting %s/%s:%s", r.namespace, imageStreamName, tag)
+
+		err := r.client.ImageStreamTags(r.namespace).Delete(ctx, BuildImageStreamTagName(imageStreamName, tag), metav1.DeleteOptions{})
+		if err != nil {
+			log.WithError(err).Errorf("Failed to delete %s/%s:%s", r.namespace, imageStreamName, tag)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d image stream tags", failed, len(tags))
+	}
+	return nil
+}
+
+// GetActiveImages returns the given image digests of the image stream that are referenced by digest or running in the
+// namespace or in the additional namespaces of the options, or present in an image stream of the promotion namespaces.
+// References by tag only concern the newest image of a tag and are not considered, regardless of the reference mode.
+func (r *ImageStreamRepository) GetActiveImages(ctx context.Context, imageStreamName string, digests []string) ([]string, error) {
+	// each digest is a pseudo tag whose history only contains the digest itself
+	target := ImageTarget{
+		Namespace: r.namespace,
+		Name:      imageStreamName,
+		Digests:   make(map[string][]string, len(digests)),
+	}
+	for _, digest := range digests {
+		target.Digests[digest] = []string{digest}
+	}
+	namespaces := r.activeOptions.SearchNamespaces(r.namespace)
+	referencedImages, err := GetReferencedImageTags(ctx, r.helper, r.activeOptions.ReferenceResources(), namespaces, target, digests)
+	if err != nil {
+		return nil, err
+	}
+	runningImages, err := GetRunningImageTags(ctx, r.helper, namespaces, target, funk.SubtractString(digests, referencedImages))
+	if err != nil {
+		return nil, err
+	}
+	return r.appendPromotedTags(ctx, append(referencedImages, runningImages...), target, digests)
+}
+
+// DeleteHistoryItems removes the items with the given image digests from the history of the tags by updating the
+// status of the image stream, the same way pruning images does. The newest item of a tag is never removed.
+func (r *ImageStreamRepository) DeleteHistoryItems(ctx context.Context, imageStreamName string, items map[string][]string) error {
+	delete(r.tags, imageStreamName)
+	imageStream, err := r.client.ImageStreams(r.namespace).Get(ctx, imageStreamName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	removed := 0
+	for i, imageTag := range imageStream.Status.Tags {
+		digests := items[imageTag.Tag]
+		if len(digests) == 0 {
+			continue
+		}
+		keptItems := make([]imagev1.TagEvent, 0, len(imageTag.Items))
+		for j, item := range imageTag.Items {
+			if j > 0 && funk.ContainsString(digests, item.Image) {
+				log.Infof("Deleting %s/%s:%s@%s", r.namespace, imageStreamName, imageTag.Tag, item.Image)
+				removed++
+				continue
+			}
+			keptItems = append(keptItems, item)
+		}
+		imageStream.Status.Tags[i].Items = keptItems
+	}
+	if removed == 0 {
+		return nil
+	}
+	if _, err := r.client.ImageStreams(r.namespace).UpdateStatus(ctx, imageStream, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not remove %d history items of image stream '%s': %w", removed, imageStreamName, err)
+	}
+	return nil
+}
+
+// ListImages returns the names of the image streams in the namespace matching the label selector
+func (r *ImageStreamRepository) ListImages(ctx context.Context, selector string) ([]string, error) {
+	imageStreams, err := r.ListImageStreams(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(imageStreams))
+	for _, imageStream := range imageStreams {
+		names = append(names, imageStream.Name)
+	}
+	return names, nil
+}
+
+// ListImageStreams lists the available image streams in the namespace
+func (r *ImageStreamRepository) ListImageStreams(ctx context.Context, listOptions metav1.ListOptions) ([]imagev1.ImageStream, error) {
+	imageStreams, err := r.client.ImageStreams(r.namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, err
+	}
+	return imageStreams.Items, nil
+}
+
+// imageMetadata contains the fields of the "dockerImageMetadata" of an image that are needed to resolve its blobs
+type imageMetadata struct {
+	// ID is the digest of the image config
+	ID string `json:"Id"`
+	// Size is the size of the layers and the config
+	Size int64 `json:"Size"`
+}
+
+// GetImageBlobs returns the size in bytes of the layers and the config of the image, read from the Images API by the
+// image digest
+func (r *ImageStreamRepository) GetImageBlobs(ctx context.Context, digest string) (map[string]int64, error) {
+	image, err := r.client.Images().Get(ctx, digest, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return imageBlobs(image)
+}
+
+// imageBlobs returns the size of the layers of the image by their digest. The size of the config is the remainder of
+// the image size in the metadata. Images without layer information are counted as a single blob of the image size.
+func imageBlobs(image *imagev1.Image) (map[string]int64, error) {
+	metadata := imageMetadata{}
+	if len(image.DockerImageMetadata.Raw) > 0 {
+		if err := json.Unmarshal(image.DockerImageMetadata.Raw, &metadata); err != nil {
+			return nil, fmt.Errorf("could not parse metadata of image '%s': %w", image.Name, err)
+		}
+	}
+	blobs := make(map[string]int64, len(image.DockerImageLayers)+1)
+	if len(image.DockerImageLayers) == 0 {
+		blobs[image.Name] = metadata.Size
+		return blobs, nil
+	}
+	var layerSize int64
+	for _, layer := range image.DockerImageLayers {
+		blobs[layer.Name] = layer.LayerSize
+		layerSize += layer.LayerSize
+	}
+	if _, found := blobs[metadata.ID]; !found && strings.HasPrefix(metadata.ID, "sha256:") && metadata.Size > layerSize {
+		blobs[metadata.ID] = metadata.Size - layerSize
+	}
+	return blobs, nil
+}
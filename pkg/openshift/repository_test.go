@@ -0,0 +1,136 @@
+package openshift
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/appuio/seiso/pkg/cleanup"
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/openshift/client-go/image/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func Test_ListImages(t *testing.T) {
+	imageStreams := []runtime.Object{
+		&imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "namespace", Labels: map[string]string{"app": "example"}}},
+		&imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "namespace"}},
+		&imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other-namespace"}},
+	}
+	tests := map[string]struct {
+		selector string
+		expected []string
+	}{
+		"ShouldListAllImageStreams_InNamespace": {
+			expected: []string{"app", "db"},
+		},
+		"ShouldListImageStreams_MatchingSelector": {
+			selector: "app=example",
+			expected: []string{"app"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			repository := NewImageStreamRepository(fake.NewSimpleClientset(imageStreams...).ImageV1(), new(MockHelper), "namespace", ActiveImageOptions{})
+
+			names, err := repository.ListImages(context.Background(), tt.selector)
+
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, names)
+		})
+	}
+}
+
+func Test_GetTags(t *testing.T) {
+	day := func(d int) metav1.Time { return metav1.NewTime(time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC)) }
+	imageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "namespace"}}
+	imageStream.Status.Tags = []imagev1.NamedTagEventList{
+		{Tag: "v1", Items: []imagev1.TagEvent{{Image: "sha256:new", Created: day(2)}, {Image: "sha256:old", Created: day(1)}}},
+		{Tag: "v2", Items: []imagev1.TagEvent{{Created: day(3)}}},
+	}
+	repository := NewImageStreamRepository(fake.NewSimpleClientset(imageStream).ImageV1(), new(MockHelper), "namespace", ActiveImageOptions{})
+
+	imageTags, err := repository.GetTags(context.Background(), "app")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []cleanup.ImageTag{
+		{Name: "v1", Digests: []string{"sha256:new", "sha256:old"}, Created: day(2).Time},
+		{Name: "v2", Created: day(3).Time},
+	}, imageTags)
+}
+
+func Test_GetActiveTags_CachedTags(t *testing.T) {
+	imageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "namespace"}}
+	imageStream.Status.Tags = []imagev1.NamedTagEventList{{Tag: "v1", Items: []imagev1.TagEvent{{Image: "sha256:v1"}}}}
+	clientset := fake.NewSimpleClientset(imageStream)
+	helper := new(MockHelper)
+	helper.On("ListResources", mock.Anything, mock.Anything).Return([]unstructured.Unstructured{}, nil)
+	repository := NewImageStreamRepository(clientset.ImageV1(), helper, "namespace", ActiveImageOptions{})
+	_, err := repository.GetTags(context.Background(), "app")
+	require.NoError(t, err)
+
+	activeTags, err := repository.GetActiveTags(context.Background(), "app", []string{"v1"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, activeTags)
+	gets := 0
+	for _, action := range clientset.Actions() {
+		if action.Matches("get", "imagestreams") {
+			gets++
+		}
+	}
+	assert.Equal(t, 1, gets, "the image stream is read once")
+}
+
+func Test_imageBlobs(t *testing.T) {
+	layers := []imagev1.ImageLayer{{Name: "sha256:base", LayerSize: 100}, {Name: "sha256:app", LayerSize: 20}}
+	tests := map[string]struct {
+		image    imagev1.Image
+		expected map[string]int64
+	}{
+		"GivenLayers_ThenAddConfigFromMetadata": {
+			image: imagev1.Image{
+				DockerImageLayers:   layers,
+				DockerImageMetadata: runtime.RawExtension{Raw: []byte(`{"Id":"sha256:config","Size":123}`)},
+			},
+			expected: map[string]int64{"sha256:base": 100, "sha256:app": 20, "sha256:config": 3},
+		},
+		"GivenLayersWithoutMetadata_ThenReturnLayers": {
+			image:    imagev1.Image{DockerImageLayers: layers},
+			expected: map[string]int64{"sha256:base": 100, "sha256:app": 20},
+		},
+		"GivenNoLayers_ThenReturnImageSize": {
+			image: imagev1.Image{
+				ObjectMeta:          metav1.ObjectMeta{Name: "sha256:image"},
+				DockerImageMetadata: runtime.RawExtension{Raw: []byte(`{"Id":"sha256:config","Size":123}`)},
+			},
+			expected: map[string]int64{"sha256:image": 123},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			blobs, err := imageBlobs(&tt.image)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, blobs)
+		})
+	}
+}
+
+func Test_GetImageBlobs(t *testing.T) {
+	image := &imagev1.Image{
+		ObjectMeta:        metav1.ObjectMeta{Name: "sha256:image"},
+		DockerImageLayers: []imagev1.ImageLayer{{Name: "sha256:base", LayerSize: 100}},
+	}
+	repository := NewImageStreamRepository(fake.NewSimpleClientset(image).ImageV1(), new(MockHelper), "namespace", ActiveImageOptions{})
+
+	blobs, err := repository.GetImageBlobs(context.Background(), "sha256:image")
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"sha256:base": 100}, blobs)
+}
@@ -2,31 +2,44 @@ package openshift
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/appuio/seiso/pkg/kubernetes"
-	imagev1 "github.com/openshift/api/image/v1"
 	log "github.com/sirupsen/logrus"
 	"github.com/thoas/go-funk"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+type (
+	// ActiveImageOptions configures how active image tags are detected
+	ActiveImageOptions struct {
+		// Namespaces are searched for references of the image in addition to the namespace of the image
+		Namespaces []string
+		// PromotionNamespaces are searched for image streams that the image is promoted to
+		PromotionNamespaces []string
+		// ReferenceMode is either ReferenceModeStructured (default) or ReferenceModeSubstring
+		ReferenceMode string
+		// Resources are searched for references in addition to the predefined resources, e.g. custom resources
+		Resources []schema.GroupVersionResource
+	}
+)
+
 var (
+	// PredefinedResources are the resources searched for references. The version is resolved to the version served by
+	// the cluster, resources that are not served are skipped.
 	PredefinedResources = []schema.GroupVersionResource{
 		{Version: "v1", Resource: "pods"},
 		{Group: "apps", Version: "v1", Resource: "statefulsets"},
 		{Group: "apps", Version: "v1", Resource: "deployments"},
+		{Group: "apps", Version: "v1", Resource: "daemonsets"},
+		{Group: "apps", Version: "v1", Resource: "replicasets"},
 		{Group: "apps.openshift.io", Version: "v1", Resource: "deploymentconfigs"},
-		{Group: "batch", Version: "v1beta1", Resource: "cronjobs"},
-		{Group: "extensions", Version: "v1beta1", Resource: "daemonsets"},
-		{Group: "extensions", Version: "v1beta1", Resource: "deployments"},
-		{Group: "extensions", Version: "v1beta1", Resource: "replicasets"},
+		{Group: "batch", Version: "v1", Resource: "cronjobs"},
 	}
-	helper = kubernetes.New()
 )
 
 // GetActiveImageStreamTags retrieves the image streams tags referenced in some Kubernetes resources
-func GetActiveImageStreamTags(ctx context.Context, namespace, imageStream string, imageStreamTags []string) (activeImageStreamTags []string, funcError error) {
+func GetActiveImageStreamTags(ctx context.Context, helper kubernetes.Kubernetes, resources []schema.GroupVersionResource, namespace, imageStream string, imageStreamTags []string) (activeImageStreamTags []string, funcError error) {
 	log.WithFields(log.Fields{
 		"namespace": namespace,
 		"imageName": imageStream,
@@ -35,7 +48,7 @@ func GetActiveImageStreamTags(ctx context.Context, namespace, imageStream string
 	if len(imageStreamTags) == 0 {
 		return []string{}, nil
 	}
-	funk.ForEach(PredefinedResources, func(predefinedResource schema.GroupVersionResource) {
+	funk.ForEach(resources, func(predefinedResource schema.GroupVersionResource) {
 		funk.ForEach(imageStreamTags, func(imageStreamTag string) {
 			if funk.ContainsString(activeImageStreamTags, imageStreamTag) {
 				// already marked as existing, skip this
@@ -56,47 +69,31 @@ func GetActiveImageStreamTags(ctx context.Context, namespace, imageStream string
 	return activeImageStreamTags, funcError
 }
 
-// GetImageStreamTags returns the tags of an image stream older than the specified time
-func GetImageStreamTags(ctx context.Context, namespace, imageStreamName string) ([]imagev1.NamedTagEventList, error) {
-
-	imageClient, err := NewImageV1Client()
+// GetActiveImageStreamTagsInNamespaces retrieves the image stream tags referenced in the namespace of the image stream,
+// or as "namespace/imageStream:tag" in any of the other namespaces
+func GetActiveImageStreamTagsInNamespaces(ctx context.Context, helper kubernetes.Kubernetes, resources []schema.GroupVersionResource, namespace, imageStream string, imageStreamTags []string, otherNamespaces []string) ([]string, error) {
+	activeImageStreamTags, err := GetActiveImageStreamTags(ctx, helper, resources, namespace, imageStream, imageStreamTags)
 	if err != nil {
 		return nil, err
 	}
-
-	imageStream, err := imageClient.ImageStreams(namespace).Get(ctx, imageStreamName, metav1.GetOptions{})
-	if err != nil {
-		return nil, err
-	}
-
-	return imageStream.Status.Tags, nil
-}
-
-// DeleteImageStreamTag deletes the image stream tag
-func DeleteImageStreamTag(ctx context.Context, namespace, name string) error {
-	imageclient, err := NewImageV1Client()
-	if err != nil {
-		return err
+	for _, otherNamespace := range otherNamespaces {
+		if otherNamespace == namespace {
+			continue
+		}
+		remainingTags := funk.SubtractString(imageStreamTags, activeImageStreamTags)
+		if len(remainingTags) == 0 {
+			break
+		}
+		activeTags, err := GetActiveImageStreamTags(ctx, helper, resources, otherNamespace, namespace+"/"+imageStream, remainingTags)
+		if err != nil {
+			return nil, fmt.Errorf("could not search namespace '%s': %w", otherNamespace, err)
+		}
+		activeImageStreamTags = append(activeImageStreamTags, activeTags...)
 	}
-
-	return imageclient.ImageStreamTags(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	return activeImageStreamTags, nil
 }
 
 // BuildImageStreamTagName combines a name of an image stream and a tag
 func BuildImageStreamTagName(imageStream string, imageStreamTag string) string {
 	return imageStream + ":" + imageStreamTag
 }
-
-// ListImageStreams lists all available image streams in a namespace
-func ListImageStreams(ctx context.Context, namespace string) ([]imagev1.ImageStream, error) {
-	imageClient, err := NewImageV1Client()
-	if err != nil {
-		return nil, err
-	}
-
-	imageStreams, err := imageClient.ImageStreams(namespace).List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return nil, err
-	}
-	return imageStreams.Items, nil
-}
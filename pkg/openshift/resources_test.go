@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/thoas/go-funk"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -22,6 +23,11 @@ func (m *MockHelper) ResourceContains(_ context.Context, namespace, value string
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockHelper) ListResources(_ context.Context, namespace string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	args := m.Called(namespace, resource)
+	return args.Get(0).([]unstructured.Unstructured), args.Error(1)
+}
+
 func TestGetActiveImageStreamTags(t *testing.T) {
 	type args struct {
 		namespace       string
@@ -80,7 +86,6 @@ func TestGetActiveImageStreamTags(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx := context.Background()
-			helper = tt.helperMock
 			for _, resource := range PredefinedResources {
 				for _, tag := range tt.args.imageStreamTags {
 					value := funk.ContainsString(tt.wantActiveImageStreamTags, tag)
@@ -93,7 +98,7 @@ func TestGetActiveImageStreamTags(t *testing.T) {
 						Return(value, err)
 				}
 			}
-			result, err := GetActiveImageStreamTags(ctx, tt.args.namespace, tt.args.imageStream, tt.args.imageStreamTags)
+			result, err := GetActiveImageStreamTags(ctx, tt.helperMock, PredefinedResources, tt.args.namespace, tt.args.imageStream, tt.args.imageStreamTags)
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
@@ -0,0 +1,210 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type (
+	// Credentials holds the username and password used to authenticate against a registry
+	Credentials struct {
+		Username string
+		Password string
+		// IdentityToken is an OAuth refresh token, which replaces the password when fetching bearer tokens
+		IdentityToken string
+	}
+	// dockerConfig is the subset of the docker config.json that is relevant to authenticate against a registry
+	dockerConfig struct {
+		Auths map[string]dockerAuth `json:"auths"`
+	}
+	dockerAuth struct {
+		Auth          string `json:"auth"`
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		IdentityToken string `json:"identitytoken"`
+	}
+	tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+)
+
+// DefaultDockerConfigPath returns the location of the docker config.json, honoring the DOCKER_CONFIG environment variable
+func DefaultDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// LoadCredentials reads the credentials of the given registry host (or URL) from a docker config.json. If the file does
+// not exist or does not contain an entry for the host, empty credentials are returned and anonymous access is attempted.
+func LoadCredentials(configPath, registryHost string) (Credentials, error) {
+	if configPath == "" {
+		return Credentials{}, nil
+	}
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Credentials{}, nil
+		}
+		return Credentials{}, err
+	}
+	config := dockerConfig{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return Credentials{}, fmt.Errorf("could not parse docker config %s: %w", configPath, err)
+	}
+	for key, auth := range config.Auths {
+		if ParseHost(key) != ParseHost(registryHost) {
+			continue
+		}
+		return auth.credentials()
+	}
+	return Credentials{}, nil
+}
+
+func (a dockerAuth) credentials() (Credentials, error) {
+	credentials := Credentials{Username: a.Username, Password: a.Password, IdentityToken: a.IdentityToken}
+	if a.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("could not decode auth: %w", err)
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return Credentials{}, errors.New("invalid auth, expected \"username:password\"")
+		}
+		credentials.Username, credentials.Password = parts[0], parts[1]
+	}
+	return credentials, nil
+}
+
+// IsEmpty returns true if no username and password are set
+func (c Credentials) IsEmpty() bool {
+	return c.Username == "" && c.Password == ""
+}
+
+// ParseHost strips the scheme and path of registry URLs and docker config keys like "https://index.docker.io/v1/"
+func ParseHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	return strings.SplitN(host, "/", 2)[0]
+}
+
+// parseChallenge parses a WWW-Authenticate header like `Bearer realm="https://auth",service="registry",scope="..."`
+func parseChallenge(header string) (scheme string, params map[string]string) {
+	params = map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	scheme = strings.ToLower(parts[0])
+	if len(parts) < 2 {
+		return scheme, params
+	}
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				value, rest = rest, ""
+			} else {
+				value, rest = rest[:end], rest[end:]
+			}
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return scheme, params
+}
+
+// fetchToken requests a bearer token from the token server announced in the challenge. An identity token is exchanged
+// for an access token with the OAuth2 refresh token grant, otherwise the token is requested with basic credentials.
+func (c *Client) fetchToken(ctx context.Context, params map[string]string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid token realm %q", params["realm"])
+	}
+	req, err := c.newTokenRequest(ctx, realm, params)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request to %s failed: %s", realm.Host, resp.Status)
+	}
+	token := tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("could not decode token response: %w", err)
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+	return "", errors.New("token server did not return a token")
+}
+
+// newTokenRequest creates the request of a token for the service and scope of the challenge. The identity token is
+// posted as refresh token, basic credentials are only sent without identity token.
+func (c *Client) newTokenRequest(ctx context.Context, realm *url.URL, params map[string]string) (*http.Request, error) {
+	values := url.Values{}
+	if service := params["service"]; service != "" {
+		values.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		values.Set("scope", scope)
+	}
+	if c.credentials.IdentityToken != "" {
+		values.Set("grant_type", "refresh_token")
+		values.Set("refresh_token", c.credentials.IdentityToken)
+		values.Set("client_id", "seiso")
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, realm.String(), strings.NewReader(values.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	}
+	query := realm.Query()
+	for key, value := range values {
+		query[key] = value
+	}
+	realm.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if !c.credentials.IsEmpty() {
+		req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
+	}
+	return req, nil
+}
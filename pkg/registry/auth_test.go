@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LoadCredentials(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{
+		"auths": {
+			"https://registry.example.com/v1/": {"auth": "dXNlcjpwYXNzd29yZA=="},
+			"quay.io": {"username": "robot", "password": "secret"},
+			"ghcr.io": {"auth": "PHRva2VuPjo=", "identitytoken": "refresh-token"}
+		}
+	}`), 0600))
+
+	tests := map[string]struct {
+		configPath string
+		registry   string
+		expected   Credentials
+		wantErr    bool
+	}{
+		"ShouldDecodeAuth_IfKeyIsURL": {
+			configPath: configPath,
+			registry:   "https://registry.example.com",
+			expected:   Credentials{Username: "user", Password: "password"},
+		},
+		"ShouldUseUsernameAndPassword": {
+			configPath: configPath,
+			registry:   "quay.io",
+			expected:   Credentials{Username: "robot", Password: "secret"},
+		},
+		"ShouldReadIdentityToken": {
+			configPath: configPath,
+			registry:   "ghcr.io",
+			expected:   Credentials{Username: "<token>", IdentityToken: "refresh-token"},
+		},
+		"ShouldReturnEmpty_IfRegistryIsUnknown": {
+			configPath: configPath,
+			registry:   "docker.io",
+		},
+		"ShouldReturnEmpty_IfFileIsMissing": {
+			configPath: filepath.Join(dir, "missing.json"),
+			registry:   "quay.io",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			credentials, err := LoadCredentials(tt.configPath, tt.registry)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, credentials)
+		})
+	}
+}
+
+func Test_parseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry",scope="repository:ns/app:pull,delete"`)
+
+	assert.Equal(t, "bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry",
+		"scope":   "repository:ns/app:pull,delete",
+	}, params)
+
+	scheme, params = parseChallenge(`Basic realm=registry`)
+	assert.Equal(t, "basic", scheme)
+	assert.Equal(t, map[string]string{"realm": "registry"}, params)
+}
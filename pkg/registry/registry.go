@@ -0,0 +1,433 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appuio/seiso/pkg/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// MediaTypeDockerManifest is the media type of a Docker image manifest, schema version 2
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	// MediaTypeDockerManifestList is the media type of a Docker manifest list (multi-arch image)
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	// MediaTypeOCIManifest is the media type of an OCI image manifest
+	MediaTypeOCIManifest = "application/vnd.oci.image.manifest.v1+json"
+	// MediaTypeOCIIndex is the media type of an OCI image index (multi-arch image)
+	MediaTypeOCIIndex = "application/vnd.oci.image.index.v1+json"
+
+	tagPageSize = 100
+)
+
+var (
+	// errNotFound is returned if the registry responds with 404 Not Found
+	errNotFound = errors.New("not found")
+	// errTagNotFound is returned by GetTag if the manifest of the tag does not exist, e.g. the tag was deleted after
+	// listing the tags
+	errTagNotFound        = errors.New("tag not found")
+	acceptedManifestTypes = []string{MediaTypeDockerManifest, MediaTypeDockerManifestList, MediaTypeOCIManifest, MediaTypeOCIIndex}
+)
+
+type (
+	// Client talks to a registry implementing the Docker Registry HTTP API v2 (Distribution)
+	Client struct {
+		baseURL     *url.URL
+		httpClient  *http.Client
+		credentials Credentials
+		// tokens caches bearer tokens by the scope of the challenge
+		tokens map[string]string
+		// authorizations caches the accepted authorization by request scope, see requestScope
+		authorizations map[string]string
+		mutex          sync.Mutex
+	}
+	// Tag describes a tag of a repository in the registry
+	Tag struct {
+		Name    string
+		Digest  string
+		Created time.Time
+	}
+	// Descriptor references a blob or a manifest by its digest
+	Descriptor struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+		Size      int64  `json:"size"`
+	}
+	// Manifest is the union of the fields of image manifests and manifest lists (indexes) that are relevant for seiso
+	Manifest struct {
+		MediaType string       `json:"mediaType"`
+		Config    Descriptor   `json:"config"`
+		Layers    []Descriptor `json:"layers"`
+		Manifests []Descriptor `json:"manifests"`
+		// Digest is the content digest of the manifest as reported by the registry
+		Digest string `json:"-"`
+	}
+	imageConfig struct {
+		Created time.Time `json:"created"`
+	}
+	tagList struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+)
+
+// NewClient creates a new client for the registry at the given URL. If the URL has no scheme, https is assumed. Each
+// request, including reading the response body, is cancelled after the timeout, unless it is 0.
+func NewClient(registryURL string, credentials Credentials, timeout time.Duration) (*Client, error) {
+	if !strings.Contains(registryURL, "://") {
+		registryURL = "https://" + registryURL
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(registryURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry URL: %w", err)
+	}
+	if baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid registry URL %q: missing host", registryURL)
+	}
+	return &Client{
+		baseURL:        baseURL,
+		httpClient:     &http.Client{Timeout: timeout},
+		credentials:    credentials,
+		tokens:         map[string]string{},
+		authorizations: map[string]string{},
+	}, nil
+}
+
+// Host returns the host (and port) of the registry, as used in image references
+func (c *Client) Host() string {
+	return c.baseURL.Host
+}
+
+// ListTags returns all tag names of the given repository
+func (c *Client) ListTags(ctx context.Context, repository string) ([]string, error) {
+	var tags []string
+	next := fmt.Sprintf("/v2/%s/tags/list?n=%d", repository, tagPageSize)
+	for next != "" {
+		resp, err := c.do(ctx, http.MethodGet, repository, next, nil)
+		if err != nil {
+			return nil, err
+		}
+		list := tagList{}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not decode tag list of %s: %w", repository, err)
+		}
+		tags = append(tags, list.Tags...)
+		if next, err = c.nextPage(resp.Header.Get("Link")); err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
+
+// nextPage returns the URL of the "next" link, or "" on the last page. Links to another scheme or host than the
+// registry are rejected, so that the credentials are never sent elsewhere.
+func (c *Client) nextPage(header string) (string, error) {
+	link := util.NextLink(header)
+	if link == "" {
+		return "", nil
+	}
+	next, err := c.baseURL.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid next link %q: %w", link, err)
+	}
+	if next.Scheme != c.baseURL.Scheme || next.Host != c.baseURL.Host {
+		return "", fmt.Errorf("refusing to follow next link to %s://%s outside of the registry", next.Scheme, next.Host)
+	}
+	return next.String(), nil
+}
+
+// GetManifest returns the manifest of the given tag or digest
+func (c *Client) GetManifest(ctx context.Context, repository, reference string) (*Manifest, error) {
+	resp, err := c.do(ctx, http.MethodGet, repository, fmt.Sprintf("/v2/%s/manifests/%s", repository, reference), map[string]string{
+		"Accept": strings.Join(acceptedManifestTypes, ", "),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(body, manifest); err != nil {
+		return nil, fmt.Errorf("could not decode manifest %s:%s: %w", repository, reference, err)
+	}
+	if manifest.MediaType == "" {
+		manifest.MediaType = resp.Header.Get("Content-Type")
+	}
+	manifest.Digest = resp.Header.Get("Docker-Content-Digest")
+	if manifest.Digest == "" {
+		manifest.Digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+	return manifest, nil
+}
+
+// GetTag resolves the digest and the creation date of the given tag. The creation date is read from the image
+// configuration; for manifest lists the first referenced image is used.
+func (c *Client) GetTag(ctx context.Context, repository, tag string) (Tag, error) {
+	manifest, err := c.GetManifest(ctx, repository, tag)
+	if errors.Is(err, errNotFound) {
+		return Tag{}, fmt.Errorf("%w: %s:%s", errTagNotFound, repository, tag)
+	}
+	if err != nil {
+		return Tag{}, err
+	}
+	result := Tag{Name: tag, Digest: manifest.Digest}
+	imageManifest := manifest
+	if imageManifest.IsIndex() {
+		if len(manifest.Manifests) == 0 {
+			return result, nil
+		}
+		if imageManifest, err = c.GetManifest(ctx, repository, manifest.Manifests[0].Digest); err != nil {
+			return Tag{}, err
+		}
+	}
+	if imageManifest.Config.Digest == "" {
+		return result, nil
+	}
+	resp, err := c.do(ctx, http.MethodGet, repository, fmt.Sprintf("/v2/%s/blobs/%s", repository, imageManifest.Config.Digest), nil)
+	if err != nil {
+		return Tag{}, err
+	}
+	defer resp.Body.Close()
+	config := imageConfig{}
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return Tag{}, fmt.Errorf("could not decode image config of %s:%s: %w", repository, tag, err)
+	}
+	result.Created = config.Created
+	return result, nil
+}
+
+// GetTags lists all tags of the repository and resolves their digest and creation date. Listed tags whose manifest
+// does not exist are skipped with a warning.
+func (c *Client) GetTags(ctx context.Context, repository string) ([]Tag, error) {
+	names, err := c.ListTags(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]Tag, 0, len(names))
+	for _, name := range names {
+		tag, err := c.GetTag(ctx, repository, name)
+		if errors.Is(err, errTagNotFound) {
+			log.Warnf("Skipping tag %s of %s/%s, its manifest was not found", name, c.Host(), repository)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// DeleteManifest deletes the manifest with the given digest. Note that this removes every tag pointing to the digest.
+func (c *Client) DeleteManifest(ctx context.Context, repository, digest string) error {
+	resp, err := c.do(ctx, http.MethodDelete, repository, fmt.Sprintf("/v2/%s/manifests/%s", repository, digest), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetReferrers returns the manifests referring to the given digest as their subject, e.g. signatures and attestations,
+// using the OCI referrers API. Registries not supporting the API return no referrers.
+func (c *Client) GetReferrers(ctx context.Context, repository, digest string) ([]Descriptor, error) {
+	resp, err := c.do(ctx, http.MethodGet, repository, fmt.Sprintf("/v2/%s/referrers/%s", repository, digest), map[string]string{
+		"Accept": MediaTypeOCIIndex,
+	})
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	index := Manifest{}
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("could not decode referrers of %s@%s: %w", repository, digest, err)
+	}
+	return index.Manifests, nil
+}
+
+// IsIndex returns true if the manifest is a manifest list or an OCI index
+func (m *Manifest) IsIndex() bool {
+	return m.MediaType == MediaTypeDockerManifestList || m.MediaType == MediaTypeOCIIndex || (m.MediaType == "" && len(m.Manifests) > 0)
+}
+
+// do executes a request against the registry and retries once with authentication if the registry challenges it. The
+// authorization accepted for the same repository and method is sent right away, which avoids a 401 round trip.
+func (c *Client) do(ctx context.Context, method, repository, path string, headers map[string]string) (*http.Response, error) {
+	target, err := c.baseURL.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	scope := requestScope(method, repository)
+	cached := c.cachedAuthorization(scope)
+	resp, err := c.send(ctx, method, target.String(), headers, cached)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		drain(resp)
+		authorization, err := c.authorize(ctx, challenge, scope, cached)
+		if err != nil {
+			return nil, fmt.Errorf("could not authenticate against %s: %w", c.Host(), err)
+		}
+		if resp, err = c.send(ctx, method, target.String(), headers, authorization); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		drain(resp)
+		return nil, fmt.Errorf("%s %s failed: %w", method, target.Path, errNotFound)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		drain(resp)
+		return nil, fmt.Errorf("%s %s failed: %s", method, target.Path, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) send(ctx context.Context, method, target string, headers map[string]string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	log.WithFields(log.Fields{
+		"method": method,
+		"url":    target,
+	}).Debug("Sending registry request")
+	return c.httpClient.Do(req)
+}
+
+// authorize answers the given challenge with either basic credentials or a bearer token and caches the authorization
+// for the request scope. The rejected authorization is removed from the cache of the request scope and of the challenge
+// scope, e.g. an expired token, so that a new token is fetched. Tokens of other scopes are kept.
+func (c *Client) authorize(ctx context.Context, challenge, scope, rejected string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	c.forgetAuthorization(scope, params["scope"], rejected)
+	switch scheme {
+	case "basic":
+		if c.credentials.IsEmpty() {
+			return "", fmt.Errorf("registry requires credentials, none found")
+		}
+		req := http.Request{Header: http.Header{}}
+		req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
+		authorization := req.Header.Get("Authorization")
+		c.cacheAuthorization(scope, authorization)
+		return authorization, nil
+	case "bearer":
+		c.mutex.Lock()
+		cached, found := c.tokens[params["scope"]]
+		c.mutex.Unlock()
+		if found {
+			c.cacheAuthorization(scope, cached)
+			return cached, nil
+		}
+		token, err := c.fetchToken(ctx, params)
+		if err != nil {
+			return "", err
+		}
+		authorization := "Bearer " + token
+		c.mutex.Lock()
+		c.tokens[params["scope"]] = authorization
+		c.mutex.Unlock()
+		c.cacheAuthorization(scope, authorization)
+		return authorization, nil
+	default:
+		return "", fmt.Errorf("unsupported authentication scheme %q", scheme)
+	}
+}
+
+// requestScope returns the key of the authorization cache for requests of the method to the repository. Registries
+// require a wider token scope for deleting than for reading.
+func requestScope(method, repository string) string {
+	action := "pull"
+	if method == http.MethodDelete {
+		action = "delete"
+	}
+	return fmt.Sprintf("repository:%s:%s", repository, action)
+}
+
+// forgetAuthorization removes the rejected authorization from the cache of the request scope and the token scope
+func (c *Client) forgetAuthorization(scope, tokenScope, rejected string) {
+	if rejected == "" {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.authorizations[scope] == rejected {
+		delete(c.authorizations, scope)
+	}
+	if c.tokens[tokenScope] == rejected {
+		delete(c.tokens, tokenScope)
+	}
+}
+
+// cacheAuthorization caches the authorization that was accepted for the request scope
+func (c *Client) cacheAuthorization(scope, authorization string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.authorizations[scope] = authorization
+}
+
+// cachedAuthorization returns the authorization that was accepted for the request scope
+func (c *Client) cachedAuthorization(scope string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.authorizations[scope]
+}
+
+func drain(resp *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// GroupByDigest returns the tag names which should be deleted grouped by their digest. Tags sharing the digest with
+// a tag that is not going to be deleted are skipped, since deleting the manifest would remove the other tag as well.
+func GroupByDigest(tags []Tag, names []string) (digests map[string][]string, skipped []string) {
+	toDelete := make(map[string]bool, len(names))
+	for _, name := range names {
+		toDelete[name] = true
+	}
+	protectedDigests := map[string]bool{}
+	for _, tag := range tags {
+		if !toDelete[tag.Name] {
+			protectedDigests[tag.Digest] = true
+		}
+	}
+	digests = map[string][]string{}
+	for _, tag := range tags {
+		if !toDelete[tag.Name] {
+			continue
+		}
+		if protectedDigests[tag.Digest] {
+			skipped = append(skipped, tag.Name)
+			continue
+		}
+		digests[tag.Digest] = append(digests[tag.Digest], tag.Name)
+	}
+	return digests, skipped
+}
@@ -0,0 +1,453 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appuio/seiso/pkg/openshift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testRepository = "namespace/app"
+
+type fakeRegistry struct {
+	server *httptest.Server
+	// tags maps tag names to manifest digests
+	tags map[string]string
+	// manifests maps digests to manifest content
+	manifests map[string]Manifest
+	// blobs maps config digests to the creation date
+	blobs map[string]time.Time
+	// referrers maps digests to the digests of their referrers. If nil, the referrers API is not supported.
+	referrers map[string][]string
+	deleted   []string
+	token     string
+	// tokenRequests counts the requests of bearer tokens
+	tokenRequests int
+	// tagListRequests counts the requests of the tag list
+	tagListRequests int
+	// nextURL is prepended to the "next" links of the tag list, which are relative if empty
+	nextURL string
+	// statuses maps paths below the repository to the status the registry fails with
+	statuses map[string]int
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	r := &fakeRegistry{
+		tags:      map[string]string{},
+		manifests: map[string]Manifest{},
+		blobs:     map[string]time.Time{},
+		token:     "secret-token",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			// OAuth2 refresh token grant of identity tokens
+			_, _, hasBasicAuth := req.BasicAuth()
+			if hasBasicAuth || req.PostFormValue("grant_type") != "refresh_token" || req.PostFormValue("refresh_token") != "identity-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		} else if user, password, ok := req.BasicAuth(); !ok || user != "user" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		scope := req.FormValue("scope")
+		assert.Contains(t, []string{testScope(http.MethodGet), testScope(http.MethodDelete)}, scope)
+		r.tokenRequests++
+		_ = json.NewEncoder(w).Encode(tokenResponse{Token: r.token + "/" + scope})
+	})
+	mux.HandleFunc("/v2/", r.serveV2)
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+// testScope returns the token scope the fake registry requires for requests of the method
+func testScope(method string) string {
+	if method == http.MethodDelete {
+		return "repository:" + testRepository + ":delete"
+	}
+	return "repository:" + testRepository + ":pull"
+}
+
+func (r *fakeRegistry) addImage(tag, digest string, created time.Time) {
+	configDigest := "sha256:config-" + digest
+	r.tags[tag] = digest
+	r.manifests[digest] = Manifest{MediaType: MediaTypeDockerManifest, Config: Descriptor{Digest: configDigest}}
+	r.blobs[configDigest] = created
+}
+
+func (r *fakeRegistry) serveV2(w http.ResponseWriter, req *http.Request) {
+	// like the distribution registry, a token is only valid for the scope of the method
+	if scope := testScope(req.Method); req.Header.Get("Authorization") != "Bearer "+r.token+"/"+scope {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake",scope="%s"`, r.server.URL, scope))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(req.URL.Path, "/v2/"+testRepository+"/")
+	if status, found := r.statuses[path]; found {
+		w.WriteHeader(status)
+		return
+	}
+	switch {
+	case path == "tags/list":
+		r.tagListRequests++
+		r.serveTagList(w, req)
+	case strings.HasPrefix(path, "manifests/") && req.Method == http.MethodDelete:
+		digest := strings.TrimPrefix(path, "manifests/")
+		if _, found := r.manifests[digest]; !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r.deleted = append(r.deleted, digest)
+		w.WriteHeader(http.StatusAccepted)
+	case strings.HasPrefix(path, "manifests/"):
+		reference := strings.TrimPrefix(path, "manifests/")
+		digest, isTag := r.tags[reference]
+		if !isTag {
+			digest = reference
+		}
+		manifest, found := r.manifests[digest]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", manifest.MediaType)
+		w.Header().Set("Docker-Content-Digest", digest)
+		_ = json.NewEncoder(w).Encode(manifest)
+	case strings.HasPrefix(path, "referrers/") && r.referrers != nil:
+		index := Manifest{MediaType: MediaTypeOCIIndex, Manifests: []Descriptor{}}
+		for _, referrer := range r.referrers[strings.TrimPrefix(path, "referrers/")] {
+			index.Manifests = append(index.Manifests, Descriptor{MediaType: MediaTypeOCIManifest, Digest: referrer})
+		}
+		_ = json.NewEncoder(w).Encode(index)
+	case strings.HasPrefix(path, "blobs/"):
+		created, found := r.blobs[strings.TrimPrefix(path, "blobs/")]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(imageConfig{Created: created})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// serveTagList returns the tags in pages of size n, linking to the next page like the distribution registry does
+func (r *fakeRegistry) serveTagList(w http.ResponseWriter, req *http.Request) {
+	var names []string
+	for name := range r.tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	last := req.URL.Query().Get("last")
+	n, _ := strconv.Atoi(req.URL.Query().Get("n"))
+	start := sort.SearchStrings(names, last)
+	if last != "" && start < len(names) && names[start] == last {
+		start++
+	}
+	end := len(names)
+	if n > 0 && start+n < end {
+		end = start + n
+		w.Header().Set("Link", fmt.Sprintf(`<%s/v2/%s/tags/list?last=%s&n=%d>; rel="next"`, r.nextURL, testRepository, names[end-1], n))
+	}
+	_ = json.NewEncoder(w).Encode(tagList{Name: testRepository, Tags: names[start:end]})
+}
+
+func newTestClient(t *testing.T, r *fakeRegistry) *Client {
+	client, err := NewClient(r.server.URL, Credentials{Username: "user", Password: "password"}, time.Minute)
+	require.NoError(t, err)
+	return client
+}
+
+func Test_ListTags(t *testing.T) {
+	r := newFakeRegistry(t)
+	var expected []string
+	for i := 0; i < tagPageSize+5; i++ {
+		tag := fmt.Sprintf("tag-%03d", i)
+		r.addImage(tag, fmt.Sprintf("sha256:%03d", i), time.Now())
+		expected = append(expected, tag)
+	}
+
+	tags, err := newTestClient(t, r).ListTags(context.Background(), testRepository)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, tags)
+}
+
+func Test_ListTags_ForeignNextLink(t *testing.T) {
+	r := newFakeRegistry(t)
+	for i := 0; i < tagPageSize+5; i++ {
+		r.addImage(fmt.Sprintf("tag-%03d", i), fmt.Sprintf("sha256:%03d", i), time.Now())
+	}
+	var foreignRequests int
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		foreignRequests++
+	}))
+	t.Cleanup(foreign.Close)
+	r.nextURL = foreign.URL
+
+	_, err := newTestClient(t, r).ListTags(context.Background(), testRepository)
+
+	assert.Error(t, err)
+	assert.Zero(t, foreignRequests)
+}
+
+func Test_ListTags_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-req.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL, Credentials{}, 10*time.Millisecond)
+	require.NoError(t, err)
+
+	_, err = client.ListTags(context.Background(), testRepository)
+
+	assert.Error(t, err)
+}
+
+func Test_ListTags_ExpiredToken(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.addImage("a1", "sha256:a1", time.Now())
+	client := newTestClient(t, r)
+	_, err := client.ListTags(context.Background(), testRepository)
+	require.NoError(t, err)
+
+	// the first token is rejected from now on
+	r.token = "rotated-token"
+	tags, err := client.ListTags(context.Background(), testRepository)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, tags)
+}
+
+func Test_Client_TokenPerScope(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.addImage("a1", "sha256:a1", time.Now())
+	r.addImage("a2", "sha256:a2", time.Now())
+	client := newTestClient(t, r)
+
+	for _, digest := range []string{"sha256:a1", "sha256:a2"} {
+		_, err := client.ListTags(context.Background(), testRepository)
+		require.NoError(t, err)
+		require.NoError(t, client.DeleteManifest(context.Background(), testRepository, digest))
+	}
+
+	assert.Equal(t, 2, r.tokenRequests, "one token for pulling and one for deleting")
+}
+
+func Test_ListTags_IdentityToken(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.addImage("v1", "sha256:1", time.Now())
+	client, err := NewClient(r.server.URL, Credentials{Username: "<token>", IdentityToken: "identity-token"}, time.Minute)
+	require.NoError(t, err)
+
+	tags, err := client.ListTags(context.Background(), testRepository)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1"}, tags)
+	assert.Equal(t, 1, r.tokenRequests)
+}
+
+func Test_ListTags_WithoutCredentials(t *testing.T) {
+	r := newFakeRegistry(t)
+	client, err := NewClient(r.server.URL, Credentials{}, time.Minute)
+	require.NoError(t, err)
+
+	_, err = client.ListTags(context.Background(), testRepository)
+
+	assert.Error(t, err)
+}
+
+func Test_GetTags(t *testing.T) {
+	r := newFakeRegistry(t)
+	created := time.Date(2020, 5, 5, 10, 0, 0, 0, time.UTC)
+	r.addImage("a1", "sha256:a1", created)
+	r.addImage("latest", "sha256:a1", created)
+	r.addImage("a2", "sha256:a2", created.Add(time.Hour))
+
+	tags, err := newTestClient(t, r).GetTags(context.Background(), testRepository)
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []Tag{
+		{Name: "a1", Digest: "sha256:a1", Created: created},
+		{Name: "latest", Digest: "sha256:a1", Created: created},
+		{Name: "a2", Digest: "sha256:a2", Created: created.Add(time.Hour)},
+	}, tags)
+}
+
+func Test_GetTags_MissingManifest(t *testing.T) {
+	r := newFakeRegistry(t)
+	created := time.Date(2020, 5, 5, 10, 0, 0, 0, time.UTC)
+	r.addImage("a1", "sha256:a1", created)
+	r.addImage("deleted", "sha256:deleted", created)
+	r.statuses = map[string]int{"manifests/deleted": http.StatusNotFound}
+
+	tags, err := newTestClient(t, r).GetTags(context.Background(), testRepository)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{Name: "a1", Digest: "sha256:a1", Created: created}}, tags)
+}
+
+func Test_GetTags_ManifestError(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.addImage("a1", "sha256:a1", time.Now())
+	r.addImage("broken", "sha256:broken", time.Now())
+	r.statuses = map[string]int{"manifests/broken": http.StatusInternalServerError}
+
+	_, err := newTestClient(t, r).GetTags(context.Background(), testRepository)
+
+	assert.Error(t, err)
+}
+
+func Test_GetTag_ManifestList(t *testing.T) {
+	r := newFakeRegistry(t)
+	created := time.Date(2020, 5, 5, 10, 0, 0, 0, time.UTC)
+	r.addImage("amd64", "sha256:amd64", created)
+	r.tags["multi"] = "sha256:index"
+	r.manifests["sha256:index"] = Manifest{
+		MediaType: MediaTypeOCIIndex,
+		Manifests: []Descriptor{{MediaType: MediaTypeOCIManifest, Digest: "sha256:amd64"}},
+	}
+
+	tag, err := newTestClient(t, r).GetTag(context.Background(), testRepository, "multi")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Tag{Name: "multi", Digest: "sha256:index", Created: created}, tag)
+}
+
+func Test_DeleteManifest(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.addImage("a1", "sha256:a1", time.Now())
+	client := newTestClient(t, r)
+
+	assert.NoError(t, client.DeleteManifest(context.Background(), testRepository, "sha256:a1"))
+	assert.Error(t, client.DeleteManifest(context.Background(), testRepository, "sha256:unknown"))
+	assert.Equal(t, []string{"sha256:a1"}, r.deleted)
+}
+
+func Test_GetReferrers(t *testing.T) {
+	r := newFakeRegistry(t)
+	client := newTestClient(t, r)
+
+	referrers, err := client.GetReferrers(context.Background(), testRepository, "sha256:a1")
+	assert.NoError(t, err, "unsupported referrers API")
+	assert.Empty(t, referrers)
+
+	r.referrers = map[string][]string{"sha256:a1": {"sha256:sig"}}
+	referrers, err = client.GetReferrers(context.Background(), testRepository, "sha256:a1")
+	assert.NoError(t, err)
+	assert.Equal(t, []Descriptor{{MediaType: MediaTypeOCIManifest, Digest: "sha256:sig"}}, referrers)
+}
+
+func Test_Repository_DeleteTags_Referrers(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.addImage("a1", "sha256:a1", time.Now())
+	r.addImage("a2", "sha256:a2", time.Now())
+	r.addImage("signed", "sha256:sig-a2", time.Now())
+	r.manifests["sha256:sig-a1"] = Manifest{MediaType: MediaTypeOCIManifest}
+	r.referrers = map[string][]string{"sha256:a1": {"sha256:sig-a1"}, "sha256:a2": {"sha256:sig-a2"}}
+	repository := NewRepository(newTestClient(t, r), nil, "namespace", openshift.ActiveImageOptions{})
+
+	err := repository.DeleteTags(context.Background(), testRepository, []string{"a1", "a2"})
+
+	assert.NoError(t, err)
+	// the referrer of a2 is tagged by a tag that is kept
+	assert.ElementsMatch(t, []string{"sha256:a1", "sha256:sig-a1", "sha256:a2"}, r.deleted)
+}
+
+func Test_Repository_DeleteTags_CachedTags(t *testing.T) {
+	r := newFakeRegistry(t)
+	r.addImage("a1", "sha256:a1", time.Now())
+	r.addImage("a2", "sha256:a2", time.Now())
+	repository := NewRepository(newTestClient(t, r), nil, "namespace", openshift.ActiveImageOptions{})
+	_, err := repository.GetTags(context.Background(), testRepository)
+	require.NoError(t, err)
+	r.tagListRequests = 0
+
+	err = repository.DeleteTags(context.Background(), testRepository, []string{"a1"})
+
+	assert.NoError(t, err)
+	assert.Zero(t, r.tagListRequests)
+	assert.Equal(t, []string{"sha256:a1"}, r.deleted)
+}
+
+// podHelper lists the given pods and no other resources
+type podHelper []unstructured.Unstructured
+
+func (h podHelper) ResourceContains(_ context.Context, _, _ string, _ schema.GroupVersionResource) (bool, error) {
+	return false, nil
+}
+
+func (h podHelper) ListResources(_ context.Context, _ string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	if resource.Resource != "pods" {
+		return nil, nil
+	}
+	return h, nil
+}
+
+func newPod(images ...string) unstructured.Unstructured {
+	var containers []interface{}
+	for _, image := range images {
+		containers = append(containers, map[string]interface{}{"name": "app", "image": image})
+	}
+	return unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "pod", "namespace": "namespace"},
+		"spec":       map[string]interface{}{"containers": containers},
+	}}
+}
+
+func Test_Repository_GetActiveTags_RegistrySpelling(t *testing.T) {
+	client, err := NewClient("https://Registry.Example.com:443", Credentials{}, time.Minute)
+	require.NoError(t, err)
+	helper := podHelper{newPod("registry.example.com/namespace/app:v1", "registry.example.com:5000/namespace/app:v2")}
+	repository := NewRepository(client, helper, "namespace", openshift.ActiveImageOptions{})
+
+	activeTags, err := repository.GetActiveTags(context.Background(), testRepository, []string{"v1", "v2", "v3"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v1"}, activeTags)
+}
+
+func Test_GroupByDigest(t *testing.T) {
+	tags := []Tag{
+		{Name: "a1", Digest: "sha256:1"},
+		{Name: "latest", Digest: "sha256:1"},
+		{Name: "a2", Digest: "sha256:2"},
+		{Name: "a2-debug", Digest: "sha256:2"},
+		{Name: "a3", Digest: "sha256:3"},
+	}
+
+	digests, skipped := GroupByDigest(tags, []string{"a1", "a2", "a2-debug"})
+
+	assert.Equal(t, map[string][]string{"sha256:2": {"a2", "a2-debug"}}, digests)
+	assert.Equal(t, []string{"a1"}, skipped)
+}
+
+func Test_NewClient(t *testing.T) {
+	client, err := NewClient("registry.example.com:5000/", Credentials{}, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.example.com:5000", client.Host())
+	assert.Equal(t, "https", client.baseURL.Scheme)
+	assert.Equal(t, time.Minute, client.httpClient.Timeout)
+
+	_, err = NewClient("https://", Credentials{}, time.Minute)
+	assert.Error(t, err)
+}
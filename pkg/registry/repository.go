@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/appuio/seiso/pkg/cleanup"
+	"github.com/appuio/seiso/pkg/kubernetes"
+	"github.com/appuio/seiso/pkg/openshift"
+	log "github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
+)
+
+// Repository provides the tags of images stored in a registry. Active tags are looked up in the given namespace and
+// the additional namespaces of the options.
+type Repository struct {
+	client        *Client
+	helper        kubernetes.Kubernetes
+	namespace     string
+	activeOptions openshift.ActiveImageOptions
+	// tags caches the tags of the repositories read by GetTags, their digests are used to find references by digest
+	// and to delete the manifests of the tags
+	tags map[string][]Tag
+}
+
+// NewRepository creates a new Repository for the registry
+func NewRepository(client *Client, helper kubernetes.Kubernetes, namespace string, activeOptions openshift.ActiveImageOptions) *Repository {
+	return &Repository{
+		client:        client,
+		helper:        helper,
+		namespace:     namespace,
+		activeOptions: activeOptions,
+		tags:          map[string][]Tag{},
+	}
+}
+
+// GetTags returns the tags of the repository. Each tag has exactly one digest, registries do not keep the history of
+// tags.
+func (r *Repository) GetTags(ctx context.Context, repository string) ([]cleanup.ImageTag, error) {
+	tags, err := r.client.GetTags(ctx, repository)
+	if err != nil {
+		return nil, err
+	}
+	r.tags[repository] = tags
+	return toImageTags(tags), nil
+}
+
+// toImageTags converts the tags of the registry
+func toImageTags(tags []Tag) []cleanup.ImageTag {
+	imageTags := make([]cleanup.ImageTag, 0, len(tags))
+	for _, tag := range tags {
+		imageTags = append(imageTags, cleanup.ImageTag{Name: tag.Name, Digests: []string{tag.Digest}, Created: tag.Created})
+	}
+	return imageTags
+}
+
+// GetActiveTags returns the tags that are referenced in the namespace or in the additional namespaces of the options.
+// The registry host is part of the searched image name, which avoids matching images with the same repository path
+// in other registries. Images running in Pods count for the tags whose digest was read by GetTags.
+func (r *Repository) GetActiveTags(ctx context.Context, repository string, tags []string) ([]string, error) {
+	target := openshift.ImageTarget{
+		Registry: r.client.Host(),
+		Name:     repository,
+		Digests:  cleanup.TagDigests(toImageTags(r.tags[repository])),
+	}
+	if i := strings.LastIndex(repository, "/"); i >= 0 {
+		target.Namespace, target.Name = repository[:i], repository[i+1:]
+	}
+	namespaces := r.activeOptions.SearchNamespaces(r.namespace)
+	if r.activeOptions.ReferenceMode != openshift.ReferenceModeSubstring {
+		return openshift.GetReferencedImageTags(ctx, r.helper, r.activeOptions.ReferenceResources(), namespaces, target, tags)
+	}
+	image := r.client.Host() + "/" + repository
+	activeTags, err := openshift.GetActiveImageStreamTags(ctx, r.helper, r.activeOptions.SubstringResources(), r.namespace, image, tags)
+	if err != nil {
+		return nil, err
+	}
+	for _, namespace := range r.activeOptions.Namespaces {
+		remainingTags := funk.SubtractString(tags, activeTags)
+		if namespace == r.namespace || len(remainingTags) == 0 {
+			continue
+		}
+		found, err := openshift.GetActiveImageStreamTags(ctx, r.helper, r.activeOptions.SubstringResources(), namespace, image, remainingTags)
+		if err != nil {
+			return nil, fmt.Errorf("could not search namespace '%s': %w", namespace, err)
+		}
+		activeTags = append(activeTags, found...)
+	}
+	runningTags, err := openshift.GetRunningImageTags(ctx, r.helper, namespaces, target, funk.SubtractString(tags, activeTags))
+	if err != nil {
+		return nil, err
+	}
+	return append(activeTags, runningTags...), nil
+}
+
+// getCachedTags returns the tags read by GetTags, so that the manifests of the tags are not resolved again. The tags
+// are read if GetTags has not been called for the repository.
+func (r *Repository) getCachedTags(ctx context.Context, repository string) ([]Tag, error) {
+	if tags, found := r.tags[repository]; found {
+		return tags, nil
+	}
+	if _, err := r.GetTags(ctx, repository); err != nil {
+		return nil, err
+	}
+	return r.tags[repository], nil
+}
+
+// DeleteTags deletes the manifests of the given tags. Tags sharing their digest with a tag that is kept are skipped,
+// as deleting the manifest would remove the kept tag too. The OCI referrers of the deleted manifests, e.g. signatures
+// and attestations, are deleted with them.
+func (r *Repository) DeleteTags(ctx context.Context, repository string, tags []string) error {
+	allTags, err := r.getCachedTags(ctx, repository)
+	if err != nil {
+		return err
+	}
+	digests, skipped := GroupByDigest(allTags, tags)
+	for _, tag := range skipped {
+		log.Warnf("Skipping %s/%s:%s, its manifest is shared with a tag that is kept", r.client.Host(), repository, tag)
+	}
+	keptDigests := map[string]bool{}
+	for _, tag := range allTags {
+		if !funk.ContainsString(tags, tag.Name) {
+			keptDigests[tag.Digest] = true
+		}
+	}
+	failed, total := 0, 0
+	for digest, digestTags := range digests {
+		// the referrers are resolved before their subject is deleted, as some registries drop the index with it
+		referrers, err := r.client.GetReferrers(ctx, repository, digest)
+		if err != nil {
+			log.WithError(err).Warnf("Could not list referrers of %s/%s@%s", r.client.Host(), repository, digest)
+		}
+		log.Infof("Deleting %s/%s@%s (tags %v)", r.client.Host(), repository, digest, digestTags)
+		total++
+		if err := r.client.DeleteManifest(ctx, repository, digest); err != nil {
+			log.WithError(err).Errorf("Failed to delete %s/%s@%s", r.client.Host(), repository, digest)
+			failed++
+			continue
+		}
+		for _, referrer := range referrers {
+			if _, isDeleted := digests[referrer.Digest]; isDeleted || keptDigests[referrer.Digest] {
+				continue
+			}
+			log.Infof("Deleting %s/%s@%s (referrer of %s)", r.client.Host(), repository, referrer.Digest, digest)
+			total++
+			if err := r.client.DeleteManifest(ctx, repository, referrer.Digest); err != nil && !errors.Is(err, errNotFound) {
+				log.WithError(err).Errorf("Failed to delete %s/%s@%s", r.client.Host(), repository, referrer.Digest)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d manifests", failed, total)
+	}
+	return nil
+}
@@ -26,6 +26,10 @@ type (
 	}
 	ServiceConfiguration struct {
 		Batch bool
+		// Resources are searched for references in addition to the predefined resources, e.g. custom resources
+		Resources []schema.GroupVersionResource
+		// ReferenceMode is either openshift.ReferenceModeStructured (default) or openshift.ReferenceModeSubstring
+		ReferenceMode string
 	}
 )
 
@@ -47,8 +51,12 @@ func (ss SecretsService) List(ctx context.Context, listOptions metav1.ListOption
 }
 
 func (ss SecretsService) GetUnused(ctx context.Context, namespace string, resources []v1.Secret) (unusedResources []v1.Secret, funcErr error) {
+	if ss.configuration.ReferenceMode != openshift.ReferenceModeSubstring {
+		return ss.getUnreferenced(ctx, namespace, resources)
+	}
 	var usedSecrets []v1.Secret
-	funk.ForEach(openshift.PredefinedResources, func(predefinedResource schema.GroupVersionResource) {
+	searchedResources := append(append([]schema.GroupVersionResource{}, openshift.PredefinedResources...), ss.configuration.Resources...)
+	funk.ForEach(searchedResources, func(predefinedResource schema.GroupVersionResource) {
 		funk.ForEach(resources, func(secret v1.Secret) {
 
 			secretName := secret.GetName()
@@ -78,6 +86,24 @@ func (ss SecretsService) GetUnused(ctx context.Context, namespace string, resour
 	return unusedResources, funcErr
 }
 
+// getUnreferenced returns the Secrets that are not referenced by any field of the searched resources
+func (ss SecretsService) getUnreferenced(ctx context.Context, namespace string, resources []v1.Secret) ([]v1.Secret, error) {
+	searchedResources := append(append([]schema.GroupVersionResource{}, openshift.ConfigReferenceResources...), ss.configuration.Resources...)
+	references, err := openshift.GetConfigReferences(ctx, ss.helper, searchedResources, namespace, openshift.SecretKind)
+	if err != nil {
+		return nil, err
+	}
+	var unused []v1.Secret
+	for _, resource := range resources {
+		if resourceReferences, found := references[resource.GetName()]; found {
+			openshift.LogConfigReferences(namespace, resourceReferences)
+			continue
+		}
+		unused = append(unused, resource)
+	}
+	return unused, nil
+}
+
 func (ss SecretsService) Delete(ctx context.Context, secrets []v1.Secret) error {
 	for _, resource := range secrets {
 		err := ss.client.Delete(ctx, resource.Name, metav1.DeleteOptions{})
@@ -124,6 +150,19 @@ func (ss SecretsService) FilterByMaxCount(secrets []v1.Secret, keep int) (filter
 	return secrets[keep:]
 }
 
+// FilterPerGroup applies util.FilterPerGroup to the Secrets, so that frequently changing Secrets do not push out the
+// history of other groups
+func (ss SecretsService) FilterPerGroup(secrets []v1.Secret, groupBy string, olderThan time.Time, keep int) (filteredResources []v1.Secret) {
+	resources := make([]metav1.Object, len(secrets))
+	for i := range secrets {
+		resources[i] = &secrets[i]
+	}
+	for _, resource := range util.FilterPerGroup(resources, groupBy, olderThan, keep) {
+		filteredResources = append(filteredResources, *resource.(*v1.Secret))
+	}
+	return filteredResources
+}
+
 func (ss SecretsService) Print(resources []v1.Secret) {
 	if len(resources) == 0 {
 		log.Info("Nothing found to be deleted.")
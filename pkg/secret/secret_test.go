@@ -7,9 +7,11 @@ import (
 	"time"
 
 	"github.com/appuio/seiso/pkg/kubernetes"
+	"github.com/appuio/seiso/pkg/openshift"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/fake"
@@ -31,6 +33,30 @@ func (k HelperKubernetesErr) ResourceContains(_ context.Context, namespace, valu
 	return false, errors.New("error")
 }
 
+func (k HelperKubernetes) ListResources(_ context.Context, namespace string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	return nil, nil
+}
+
+func (k HelperKubernetesErr) ListResources(_ context.Context, namespace string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	return nil, errors.New("error")
+}
+
+// HelperKubernetesObjects returns the objects as pods
+type HelperKubernetesObjects struct {
+	objects []unstructured.Unstructured
+}
+
+func (k *HelperKubernetesObjects) ResourceContains(_ context.Context, namespace, value string, resource schema.GroupVersionResource) (bool, error) {
+	return false, errors.New("not supported")
+}
+
+func (k *HelperKubernetesObjects) ListResources(_ context.Context, namespace string, resource schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	if resource.Resource == "pods" {
+		return k.objects, nil
+	}
+	return nil, nil
+}
+
 var testNamespace = "testNamespace"
 
 func Test_List(t *testing.T) {
@@ -148,6 +174,29 @@ func Test_FilterByMaxCount(t *testing.T) {
 	}
 }
 
+func Test_FilterPerGroup(t *testing.T) {
+	newSecret := func(name string, year int) v1.Secret {
+		return v1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         testNamespace,
+			CreationTimestamp: metav1.Date(year, 1, 1, 0, 0, 0, 0, time.UTC),
+		}}
+	}
+	secrets := []v1.Secret{
+		newSecret("db-credentials-5d8t8h7g9k", 2012),
+		newSecret("db-credentials-b2h4c6m7t8", 2013),
+		newSecret("tls-7h8t9k2b4c", 2010),
+	}
+	service := NewSecretsService(nil, HelperKubernetes{}, ServiceConfiguration{})
+	cutOffDate := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	filtered := service.FilterPerGroup(append([]v1.Secret{}, secrets...), "", cutOffDate, 1)
+	assert.ElementsMatch(t, []v1.Secret{secrets[0], secrets[2]}, filtered)
+
+	filtered = service.FilterPerGroup(append([]v1.Secret{}, secrets...), "kustomize", cutOffDate, 1)
+	assert.ElementsMatch(t, []v1.Secret{secrets[0]}, filtered)
+}
+
 func Test_Delete(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -217,7 +266,7 @@ func Test_GetUnused(t *testing.T) {
 			if tt.expectErr {
 				helper = HelperKubernetesErr{}
 			}
-			service := NewSecretsService(nil, helper, ServiceConfiguration{})
+			service := NewSecretsService(nil, helper, ServiceConfiguration{ReferenceMode: openshift.ReferenceModeSubstring})
 			unused, err := service.GetUnused(ctx, testNamespace, tt.allSecrets)
 			if tt.expectErr {
 				assert.Error(t, err)
@@ -229,6 +278,45 @@ func Test_GetUnused(t *testing.T) {
 	}
 }
 
+func Test_GetUnused_Structured(t *testing.T) {
+	pod := unstructured.Unstructured{Object: map[string]interface{}{
+		"kind":     "Pod",
+		"metadata": map[string]interface{}{"name": "app", "namespace": testNamespace},
+		"spec": map[string]interface{}{
+			"imagePullSecrets": []interface{}{map[string]interface{}{"name": "pull-secret"}},
+			"volumes": []interface{}{map[string]interface{}{
+				"name": "data",
+				"csi": map[string]interface{}{
+					"driver":               "secrets-store.csi.k8s.io",
+					"nodePublishSecretRef": map[string]interface{}{"name": "csi-secret"},
+				},
+			}},
+			"containers": []interface{}{map[string]interface{}{
+				"name": "app",
+				"env":  []interface{}{map[string]interface{}{"name": "NAME", "value": "nameA"}},
+			}},
+		},
+	}}
+	secrets := append(generateBaseTestSecrets(),
+		v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: testNamespace}},
+		v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "csi-secret", Namespace: testNamespace}},
+	)
+	service := NewSecretsService(nil, &HelperKubernetesObjects{objects: []unstructured.Unstructured{pod}}, ServiceConfiguration{})
+
+	unused, err := service.GetUnused(context.Background(), testNamespace, secrets)
+
+	assert.NoError(t, err)
+	var names []string
+	for _, secret := range unused {
+		names = append(names, secret.Name)
+	}
+	assert.ElementsMatch(t, []string{"nameA", "nameB"}, names)
+
+	service = NewSecretsService(nil, &HelperKubernetesErr{}, ServiceConfiguration{})
+	_, err = service.GetUnused(context.Background(), testNamespace, secrets)
+	assert.Error(t, err)
+}
+
 func generateBaseTestSecrets() []v1.Secret {
 	return []v1.Secret{
 		{
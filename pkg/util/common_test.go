@@ -110,3 +110,19 @@ func zero() metav1.Time {
 		Time: time.Time{},
 	}
 }
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[string]struct {
+		bytes    int64
+		expected string
+	}{
+		"GivenBytes_ThenFormatWithoutFraction":  {bytes: 512, expected: "512 B"},
+		"GivenKibibytes_ThenFormatWithFraction": {bytes: 1536, expected: "1.5 KiB"},
+		"GivenGibibytes_ThenUseLargestUnit":     {bytes: 3 * 1024 * 1024 * 1024, expected: "3.0 GiB"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FormatBytes(tt.bytes))
+		})
+	}
+}
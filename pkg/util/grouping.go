@@ -0,0 +1,84 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// GroupByKustomize groups resources by the name without the hash suffix added by Kustomize generators
+	GroupByKustomize = "kustomize"
+	// GroupByLabelPrefix groups resources by the value of the label key following the prefix, e.g. "label:app"
+	GroupByLabelPrefix = "label:"
+)
+
+// kustomizeHashSuffix matches the "-<hash>" suffix that Kustomize appends to generated ConfigMaps and Secrets. The hash
+// has 10 characters encoded without vowels and ambiguous characters.
+var kustomizeHashSuffix = regexp.MustCompile(`-[2456789bcdfghkmt]{10}$`)
+
+// ValidateGroupBy returns an error if the value is neither empty, GroupByKustomize nor a label key with GroupByLabelPrefix
+func ValidateGroupBy(groupBy string) error {
+	if groupBy == "" || groupBy == GroupByKustomize {
+		return nil
+	}
+	if strings.HasPrefix(groupBy, GroupByLabelPrefix) && strings.TrimPrefix(groupBy, GroupByLabelPrefix) != "" {
+		return nil
+	}
+	return fmt.Errorf("invalid grouping %q, expected \"%s\" or \"%s<key>\"", groupBy, GroupByKustomize, GroupByLabelPrefix)
+}
+
+// GroupKey returns the group of the resource. Without grouping, all resources share the empty group. Resources without
+// the label are in the empty group as well.
+func GroupKey(resource metav1.Object, groupBy string) string {
+	switch {
+	case groupBy == GroupByKustomize:
+		return kustomizeHashSuffix.ReplaceAllString(resource.GetName(), "")
+	case strings.HasPrefix(groupBy, GroupByLabelPrefix):
+		return resource.GetLabels()[strings.TrimPrefix(groupBy, GroupByLabelPrefix)]
+	}
+	return ""
+}
+
+// FilterPerGroup groups the resources (see GroupKey) and returns the resources of each group that are older than the
+// given time, except the `keep` newest ones, so that frequently changing resources do not push out the history of
+// other groups. The groups are returned ordered by key. The resources are typically pointers to ConfigMaps or Secrets,
+// which the caller converts back.
+func FilterPerGroup(resources []metav1.Object, groupBy string, olderThan time.Time, keep int) (filteredResources []metav1.Object) {
+	groups := map[string][]metav1.Object{}
+	for _, resource := range resources {
+		key := GroupKey(resource, groupBy)
+		groups[key] = append(groups[key], resource)
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		log.WithFields(log.Fields{
+			"group": key,
+			"count": len(groups[key]),
+		}).Debug("Filtering group")
+		var olderResources []metav1.Object
+		for _, resource := range groups[key] {
+			if IsOlderThan(resource, olderThan) {
+				olderResources = append(olderResources, resource)
+			}
+		}
+		if len(olderResources) <= keep {
+			continue
+		}
+		sort.SliceStable(olderResources, func(i, j int) bool {
+			return CompareTimestamps(olderResources[j].GetCreationTimestamp(), olderResources[i].GetCreationTimestamp())
+		})
+		filteredResources = append(filteredResources, olderResources[keep:]...)
+	}
+	return filteredResources
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGroupKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		resource    metav1.Object
+		groupBy     string
+		expectedKey string
+	}{
+		{
+			name:        "GivenNoGrouping_ThenReturnEmptyKey",
+			resource:    &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config-5d8t8h7g9k"}},
+			expectedKey: "",
+		},
+		{
+			name:        "GivenKustomizeGrouping_WhenNameHasHashSuffix_ThenStripSuffix",
+			resource:    &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config-5d8t8h7g9k"}},
+			groupBy:     GroupByKustomize,
+			expectedKey: "app-config",
+		},
+		{
+			name:        "GivenKustomizeGrouping_WhenNameHasNoHashSuffix_ThenReturnName",
+			resource:    &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-config-production"}},
+			groupBy:     GroupByKustomize,
+			expectedKey: "app-config-production",
+		},
+		{
+			name:        "GivenLabelGrouping_WhenLabelExists_ThenReturnLabelValue",
+			resource:    &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"component": "api"}}},
+			groupBy:     GroupByLabelPrefix + "component",
+			expectedKey: "api",
+		},
+		{
+			name:        "GivenLabelGrouping_WhenLabelMissing_ThenReturnEmptyKey",
+			resource:    &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a"}},
+			groupBy:     GroupByLabelPrefix + "component",
+			expectedKey: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedKey, GroupKey(tt.resource, tt.groupBy))
+		})
+	}
+}
+
+func TestValidateGroupBy(t *testing.T) {
+	for _, groupBy := range []string{"", GroupByKustomize, "label:app"} {
+		assert.NoError(t, ValidateGroupBy(groupBy), groupBy)
+	}
+	for _, groupBy := range []string{"name", "label:", "app"} {
+		assert.Error(t, ValidateGroupBy(groupBy), groupBy)
+	}
+}
+
+func TestFilterPerGroup(t *testing.T) {
+	newSecret := func(name string, year int) *v1.Secret {
+		return &v1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.Date(year, 1, 1, 0, 0, 0, 0, time.UTC),
+		}}
+	}
+	resources := []metav1.Object{
+		newSecret("tls-7h8t9k2b4c", 2010),
+		newSecret("db-credentials-b2h4c6m7t8", 2013),
+		newSecret("db-credentials-5d8t8h7g9k", 2012),
+		newSecret("db-credentials-g9k2h8t7m6", 2011),
+		newSecret("db-credentials-m6t5g4d2h9", 2020),
+	}
+	cutOffDate := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	filtered := FilterPerGroup(resources, GroupByKustomize, cutOffDate, 1)
+
+	var names []string
+	for _, resource := range filtered {
+		names = append(names, resource.GetName())
+	}
+	assert.Equal(t, []string{"db-credentials-5d8t8h7g9k", "db-credentials-g9k2h8t7m6"}, names)
+}
@@ -0,0 +1,16 @@
+package util
+
+import "regexp"
+
+// nextLinkRegex matches the URL of the next page in a Link header (RFC 8288), as used for pagination by registries
+// and the GitHub and GitLab APIs
+var nextLinkRegex = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+
+// NextLink returns the URL of the next page in the Link header, or an empty string if there is none
+func NextLink(header string) string {
+	matches := nextLinkRegex.FindStringSubmatch(header)
+	if len(matches) < 2 {
+		return ""
+	}
+	return matches[1]
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextLink(t *testing.T) {
+	tests := map[string]struct {
+		header   string
+		expected string
+	}{
+		"GivenRegistryLink_ThenReturnURL": {
+			header:   `</v2/app/tags/list?last=b&n=2>; rel="next"`,
+			expected: "/v2/app/tags/list?last=b&n=2",
+		},
+		"GivenSeveralLinks_ThenReturnNextURL": {
+			header:   `<https://api.github.com/repos/a/b/tags?page=1>; rel="prev", <https://api.github.com/repos/a/b/tags?page=3>; rel="next"`,
+			expected: "https://api.github.com/repos/a/b/tags?page=3",
+		},
+		"GivenUnquotedRel_ThenReturnURL": {
+			header:   `</page/2>; rel=next`,
+			expected: "/page/2",
+		},
+		"GivenNoNextLink_ThenReturnEmpty": {
+			header: `</page/1>; rel="prev"`,
+		},
+		"GivenEmptyHeader_ThenReturnEmpty": {},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NextLink(tt.header))
+		})
+	}
+}